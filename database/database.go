@@ -11,10 +11,9 @@ import (
 
 var DB *sqlx.DB
 
-func Connect() {
-	var err error
-
-	dsn := fmt.Sprintf(
+// DSN monta a string de conexão a partir das variáveis de ambiente
+func DSN() string {
+	return fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		os.Getenv("DB_HOST"),
 		os.Getenv("DB_PORT"),
@@ -23,8 +22,12 @@ func Connect() {
 		os.Getenv("DB_NAME"),
 		os.Getenv("DB_SSLMODE"),
 	)
+}
+
+func Connect() {
+	var err error
 
-	DB, err = sqlx.Connect("postgres", dsn)
+	DB, err = sqlx.Connect("postgres", DSN())
 	if err != nil {
 		log.Fatal("Erro ao conectar no banco:", err)
 	}
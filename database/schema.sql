@@ -31,10 +31,22 @@ CREATE TABLE IF NOT EXISTS questions (
     user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     title VARCHAR(200) NOT NULL,
     body TEXT NOT NULL,
+    body_html TEXT NOT NULL DEFAULT '',
     votes INTEGER DEFAULT 0,
     view_count INTEGER DEFAULT 0,
     answer_count INTEGER DEFAULT 0,
     is_solved BOOLEAN DEFAULT false,
+    closed_at TIMESTAMP,
+    closed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    close_reason VARCHAR(20) CHECK (close_reason IN ('duplicate', 'off-topic', 'unclear', 'resolved-in-game')),
+    duplicate_of INTEGER REFERENCES questions(id) ON DELETE SET NULL,
+    featured_until TIMESTAMP,
+    locked_at TIMESTAMP,
+    locked_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    bookmark_count INTEGER DEFAULT 0,
+    edited_at TIMESTAMP,
+    deleted_at TIMESTAMP,
+    deleted_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
@@ -45,8 +57,14 @@ CREATE TABLE IF NOT EXISTS answers (
     question_id INTEGER NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
     user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     body TEXT NOT NULL,
+    body_html TEXT NOT NULL DEFAULT '',
     votes INTEGER DEFAULT 0,
     is_accepted BOOLEAN DEFAULT false,
+    accepted_at TIMESTAMP,
+    bookmark_count INTEGER DEFAULT 0,
+    edited_at TIMESTAMP,
+    deleted_at TIMESTAMP,
+    deleted_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
@@ -62,6 +80,339 @@ CREATE TABLE IF NOT EXISTS votes (
     UNIQUE(user_id, post_id, post_type)
 );
 
+-- Tabela de votos para fechar/reabrir perguntas
+CREATE TABLE IF NOT EXISTS close_votes (
+    question_id INTEGER NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    vote_type VARCHAR(10) NOT NULL CHECK (vote_type IN ('close', 'reopen')),
+    reason VARCHAR(20) CHECK (reason IN ('duplicate', 'off-topic', 'unclear', 'resolved-in-game')),
+    duplicate_of INTEGER REFERENCES questions(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (question_id, user_id, vote_type)
+);
+
+-- Tabela de recompensas (bounties) em perguntas
+CREATE TABLE IF NOT EXISTS bounties (
+    id SERIAL PRIMARY KEY,
+    question_id INTEGER NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount INTEGER NOT NULL CHECK (amount > 0),
+    status VARCHAR(10) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'awarded', 'expired')),
+    awarded_answer_id INTEGER REFERENCES answers(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP NOT NULL,
+    awarded_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Tabela de rascunhos sincronizados entre dispositivos
+CREATE TABLE IF NOT EXISTS drafts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    target_type VARCHAR(20) NOT NULL CHECK (target_type IN ('question', 'answer', 'edit_question', 'edit_answer')),
+    target_id INTEGER NOT NULL DEFAULT 0,
+    title VARCHAR(200) NOT NULL DEFAULT '',
+    body TEXT NOT NULL DEFAULT '',
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, target_type, target_id)
+);
+
+-- Tabela de perguntas fixadas (tag_id nulo = fixada na listagem geral)
+CREATE TABLE IF NOT EXISTS question_pins (
+    id SERIAL PRIMARY KEY,
+    question_id INTEGER NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
+    tag_id INTEGER REFERENCES tags(id) ON DELETE CASCADE,
+    pinned_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Tabela de comentários em perguntas e respostas
+CREATE TABLE IF NOT EXISTS comments (
+    id SERIAL PRIMARY KEY,
+    post_type VARCHAR(10) NOT NULL CHECK (post_type IN ('question', 'answer')),
+    post_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body VARCHAR(600) NOT NULL,
+    votes INTEGER DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+-- Tabela de votos em comentários (apenas positivos)
+CREATE TABLE IF NOT EXISTS comment_votes (
+    comment_id INTEGER NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (comment_id, user_id)
+);
+
+-- Tabela de menções (@usuario) em perguntas, respostas e comentários
+CREATE TABLE IF NOT EXISTS mentions (
+    id SERIAL PRIMARY KEY,
+    post_type VARCHAR(10) NOT NULL CHECK (post_type IN ('question', 'answer', 'comment')),
+    post_id INTEGER NOT NULL,
+    mentioned_user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    author_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(post_type, post_id, mentioned_user_id)
+);
+
+-- Tabela de notificações (agrupadas por tipo e post enquanto não lidas)
+CREATE TABLE IF NOT EXISTS notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('answer', 'accepted', 'upvote', 'mention', 'followed_answer')),
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    actor_count INTEGER NOT NULL DEFAULT 1,
+    post_type VARCHAR(10) NOT NULL CHECK (post_type IN ('question', 'answer', 'comment')),
+    post_id INTEGER NOT NULL,
+    question_id INTEGER NOT NULL,
+    group_key VARCHAR(100) NOT NULL,
+    is_read BOOLEAN DEFAULT false,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Pessoas que compõem uma notificação agrupada
+CREATE TABLE IF NOT EXISTS notification_actors (
+    notification_id INTEGER NOT NULL REFERENCES notifications(id) ON DELETE CASCADE,
+    actor_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (notification_id, actor_id)
+);
+
+-- Preferências de notificação por tipo (ausência = habilitada)
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    PRIMARY KEY (user_id, type)
+);
+
+-- Tabela de entidades seguidas pelos usuários
+CREATE TABLE IF NOT EXISTS follows (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    target_type VARCHAR(10) NOT NULL CHECK (target_type IN ('question', 'tag', 'user')),
+    target_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, target_type, target_id)
+);
+
+-- Configuração do resumo por e-mail (ausência = desativado)
+CREATE TABLE IF NOT EXISTS digest_settings (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    frequency VARCHAR(10) NOT NULL DEFAULT 'off' CHECK (frequency IN ('off', 'daily', 'weekly')),
+    send_hour SMALLINT NOT NULL DEFAULT 12 CHECK (send_hour BETWEEN 0 AND 23),
+    last_sent_at TIMESTAMP
+);
+
+-- Tabela de webhooks de saída
+CREATE TABLE IF NOT EXISTS webhooks (
+    id SERIAL PRIMARY KEY,
+    url TEXT NOT NULL,
+    secret VARCHAR(100) NOT NULL,
+    format VARCHAR(10) NOT NULL DEFAULT 'json' CHECK (format IN ('json', 'discord')),
+    events TEXT[] NOT NULL,
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    is_active BOOLEAN DEFAULT true,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Fila durável e log de entregas dos webhooks
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id SERIAL PRIMARY KEY,
+    webhook_id INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event VARCHAR(30) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'success', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_status_code INTEGER,
+    last_error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP
+);
+
+-- Coleções de favoritos (share_token permite compartilhar coleções públicas)
+CREATE TABLE IF NOT EXISTS bookmark_collections (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    is_public BOOLEAN NOT NULL DEFAULT false,
+    share_token VARCHAR(64) UNIQUE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, name)
+);
+
+-- Posts favoritados, ordenados dentro da coleção por position
+CREATE TABLE IF NOT EXISTS bookmarks (
+    id SERIAL PRIMARY KEY,
+    collection_id INTEGER NOT NULL REFERENCES bookmark_collections(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_type VARCHAR(10) NOT NULL CHECK (post_type IN ('question', 'answer')),
+    post_id INTEGER NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(collection_id, post_type, post_id)
+);
+
+-- Arquivos enviados; o mesmo conteúdo (hash) é armazenado uma única vez
+CREATE TABLE IF NOT EXISTS attachments (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    hash VARCHAR(64) UNIQUE NOT NULL,
+    storage_key VARCHAR(200) NOT NULL,
+    thumbnail_key VARCHAR(200),
+    filename VARCHAR(255) NOT NULL,
+    mime_type VARCHAR(100) NOT NULL,
+    size INTEGER NOT NULL,
+    width INTEGER,
+    height INTEGER,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Anexos referenciados no corpo de cada post
+CREATE TABLE IF NOT EXISTS post_attachments (
+    attachment_id INTEGER NOT NULL REFERENCES attachments(id) ON DELETE CASCADE,
+    post_type VARCHAR(10) NOT NULL CHECK (post_type IN ('question', 'answer')),
+    post_id INTEGER NOT NULL,
+    PRIMARY KEY (attachment_id, post_type, post_id)
+);
+
+-- Histórico de reputação; users.reputation é a soma dos deltas
+CREATE TABLE IF NOT EXISTS reputation_events (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    delta INTEGER NOT NULL,
+    reason VARCHAR(20) NOT NULL CHECK (reason IN (
+        'upvote', 'downvote', 'downvote_given', 'vote_reversal',
+        'accepted', 'accept_given', 'accept_reversal',
+        'bounty_offered', 'bounty_awarded'
+    )),
+    post_type VARCHAR(10) CHECK (post_type IN ('question', 'answer')),
+    post_id INTEGER,
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Privilégios liberados por reputação (limites configuráveis pelo admin)
+CREATE TABLE IF NOT EXISTS privileges (
+    name VARCHAR(30) PRIMARY KEY,
+    min_reputation INTEGER NOT NULL CHECK (min_reputation >= 0),
+    description TEXT NOT NULL DEFAULT ''
+);
+
+-- Concessões ou revogações individuais, independentes da reputação
+CREATE TABLE IF NOT EXISTS user_privilege_overrides (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    privilege VARCHAR(30) NOT NULL REFERENCES privileges(name) ON DELETE CASCADE,
+    granted BOOLEAN NOT NULL,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, privilege)
+);
+
+-- Votação em série e anéis de votos detectados (fila de moderação)
+CREATE TABLE IF NOT EXISTS vote_fraud_reports (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(10) NOT NULL CHECK (kind IN ('serial', 'ring')),
+    voter_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    target_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    vote_count INTEGER NOT NULL,
+    evidence JSONB NOT NULL DEFAULT '[]',
+    status VARCHAR(10) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'confirmed', 'dismissed')),
+    reviewed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Medalhas; as de tag são criadas pelo avaliador conforme as tags recebem ganhadores
+CREATE TABLE IF NOT EXISTS badges (
+    id SERIAL PRIMARY KEY,
+    slug VARCHAR(50) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    tier VARCHAR(10) NOT NULL CHECK (tier IN ('bronze', 'silver', 'gold')),
+    tag_id INTEGER REFERENCES tags(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Medalhas conquistadas (uma vez por usuário)
+CREATE TABLE IF NOT EXISTS user_badges (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    badge_id INTEGER NOT NULL REFERENCES badges(id) ON DELETE CASCADE,
+    awarded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, badge_id)
+);
+
+-- Dias em que o usuário acessou o fórum
+CREATE TABLE IF NOT EXISTS user_visits (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    PRIMARY KEY (user_id, day)
+);
+
+-- Rankings pré-calculados pelo agendador, um por período, métrica e início do período
+CREATE TABLE IF NOT EXISTS leaderboard_snapshots (
+    id SERIAL PRIMARY KEY,
+    period VARCHAR(10) NOT NULL CHECK (period IN ('week', 'month', 'season', 'all')),
+    metric VARCHAR(20) NOT NULL CHECK (metric IN ('reputation', 'accepted', 'answer_score')),
+    period_start TIMESTAMP NOT NULL,
+    period_end TIMESTAMP NOT NULL,
+    computed_at TIMESTAMP NOT NULL,
+    UNIQUE (period, metric, period_start)
+);
+
+-- Posições de cada ranking; tag_id nulo é o ranking geral
+CREATE TABLE IF NOT EXISTS leaderboard_entries (
+    snapshot_id INTEGER NOT NULL REFERENCES leaderboard_snapshots(id) ON DELETE CASCADE,
+    tag_id INTEGER REFERENCES tags(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    rank INTEGER NOT NULL,
+    score INTEGER NOT NULL
+);
+
+-- Reações disponíveis; as personalizadas usam imagem no lugar do emoji
+CREATE TABLE IF NOT EXISTS reaction_types (
+    name VARCHAR(30) PRIMARY KEY CHECK (name ~ '^[a-z0-9_]+$'),
+    emoji VARCHAR(16),
+    image_url TEXT,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    position INTEGER NOT NULL DEFAULT 0,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (emoji IS NOT NULL OR image_url IS NOT NULL)
+);
+
+-- Reações dos usuários aos posts, independentes dos votos
+CREATE TABLE IF NOT EXISTS post_reactions (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_type VARCHAR(10) NOT NULL CHECK (post_type IN ('question', 'answer')),
+    post_id INTEGER NOT NULL,
+    reaction VARCHAR(30) NOT NULL REFERENCES reaction_types(name) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (post_type, post_id, reaction, user_id)
+);
+
+-- Sinônimos de tags, sugeridos pelos membros e aprovados pela moderação
+CREATE TABLE IF NOT EXISTS tag_synonyms (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(50) NOT NULL,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    suggested_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
 -- Tabela de relacionamento entre perguntas e tags
 CREATE TABLE IF NOT EXISTS question_tags (
     question_id INTEGER NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
@@ -69,6 +420,42 @@ CREATE TABLE IF NOT EXISTS question_tags (
     PRIMARY KEY (question_id, tag_id)
 );
 
+-- Colunas e restrições adicionadas depois da criação das tabelas, para atualizar
+-- bancos existentes (CREATE TABLE IF NOT EXISTS não altera tabelas já criadas)
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS body_html TEXT NOT NULL DEFAULT '';
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS closed_at TIMESTAMP;
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS closed_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS close_reason VARCHAR(20) CHECK (close_reason IN ('duplicate', 'off-topic', 'unclear', 'resolved-in-game'));
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS duplicate_of INTEGER REFERENCES questions(id) ON DELETE SET NULL;
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS featured_until TIMESTAMP;
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS locked_at TIMESTAMP;
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS locked_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS bookmark_count INTEGER DEFAULT 0;
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP;
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS deleted_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE answers ADD COLUMN IF NOT EXISTS body_html TEXT NOT NULL DEFAULT '';
+ALTER TABLE answers ADD COLUMN IF NOT EXISTS bookmark_count INTEGER DEFAULT 0;
+ALTER TABLE answers ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP;
+ALTER TABLE answers ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE answers ADD COLUMN IF NOT EXISTS deleted_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE answers ADD COLUMN IF NOT EXISTS accepted_at TIMESTAMP;
+
+-- Aceites anteriores a accepted_at: data do lançamento de reputação ou da última alteração
+UPDATE answers SET accepted_at = COALESCE(
+    (SELECT MAX(e.created_at) FROM reputation_events e
+     WHERE e.reason = 'accepted' AND e.post_type = 'answer' AND e.post_id = answers.id),
+    updated_at
+)
+WHERE is_accepted = true AND accepted_at IS NULL;
+
+ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_type_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_type_check CHECK (type IN ('answer', 'accepted', 'upvote', 'mention', 'followed_answer'));
+
+ALTER TABLE follows DROP CONSTRAINT IF EXISTS follows_target_type_check;
+ALTER TABLE follows ADD CONSTRAINT follows_target_type_check CHECK (target_type IN ('question', 'tag', 'user'));
+
 -- Índices para melhor performance
 CREATE INDEX IF NOT EXISTS idx_questions_user_id ON questions(user_id);
 CREATE INDEX IF NOT EXISTS idx_questions_created_at ON questions(created_at);
@@ -79,6 +466,37 @@ CREATE INDEX IF NOT EXISTS idx_votes_user_id ON votes(user_id);
 CREATE INDEX IF NOT EXISTS idx_votes_post ON votes(post_id, post_type);
 CREATE INDEX IF NOT EXISTS idx_question_tags_question_id ON question_tags(question_id);
 CREATE INDEX IF NOT EXISTS idx_question_tags_tag_id ON question_tags(tag_id);
+CREATE INDEX IF NOT EXISTS idx_questions_closed_at ON questions(closed_at);
+CREATE INDEX IF NOT EXISTS idx_questions_duplicate_of ON questions(duplicate_of);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bounties_active_question ON bounties(question_id) WHERE status = 'active';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_question_pins_unique ON question_pins(question_id, COALESCE(tag_id, 0));
+CREATE INDEX IF NOT EXISTS idx_questions_featured_until ON questions(featured_until);
+CREATE INDEX IF NOT EXISTS idx_questions_deleted_at ON questions(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_answers_deleted_at ON answers(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_post ON comments(post_id, post_type);
+CREATE INDEX IF NOT EXISTS idx_mentions_user ON mentions(mentioned_user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_users_username_lower ON users(LOWER(username));
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, updated_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_unread_group ON notifications(user_id, group_key) WHERE is_read = false;
+CREATE INDEX IF NOT EXISTS idx_follows_target ON follows(target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_bookmarks_collection ON bookmarks(collection_id, position);
+CREATE INDEX IF NOT EXISTS idx_bookmarks_post ON bookmarks(post_type, post_id);
+CREATE INDEX IF NOT EXISTS idx_post_attachments_post ON post_attachments(post_type, post_id);
+CREATE INDEX IF NOT EXISTS idx_reputation_events_user ON reputation_events(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_reputation_events_post ON reputation_events(post_type, post_id, actor_id);
+CREATE INDEX IF NOT EXISTS idx_vote_fraud_reports_status ON vote_fraud_reports(status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_votes_created_at ON votes(created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_badges_slug ON badges(slug) WHERE tag_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_badges_tag_slug ON badges(slug, tag_id) WHERE tag_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_user_badges_badge ON user_badges(badge_id, awarded_at DESC);
+CREATE INDEX IF NOT EXISTS idx_leaderboard_entries_board ON leaderboard_entries(snapshot_id, COALESCE(tag_id, 0), rank);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tag_synonyms_approved ON tag_synonyms(name) WHERE status = 'approved';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tag_synonyms_pending ON tag_synonyms(name, tag_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_tag_synonyms_tag ON tag_synonyms(tag_id, status);
+CREATE INDEX IF NOT EXISTS idx_drafts_updated_at ON drafts(updated_at);
+CREATE INDEX IF NOT EXISTS idx_bounties_expires_at ON bounties(expires_at) WHERE status = 'active';
 
 -- Trigger para atualizar updated_at automaticamente
 CREATE OR REPLACE FUNCTION update_updated_at_column()
@@ -95,6 +513,9 @@ CREATE TRIGGER update_questions_updated_at BEFORE UPDATE ON questions
 CREATE TRIGGER update_answers_updated_at BEFORE UPDATE ON answers
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+CREATE TRIGGER update_comments_updated_at BEFORE UPDATE ON comments
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
 -- Inserir algumas tags padrão
 INSERT INTO tags (name, description) VALUES 
     ('javascript', 'Linguagem de programação JavaScript'),
@@ -108,3 +529,30 @@ INSERT INTO tags (name, description) VALUES
     ('mobile', 'Desenvolvimento mobile'),
     ('ai', 'Inteligência artificial e machine learning')
 ON CONFLICT (name) DO NOTHING;
+
+-- Privilégios padrão
+INSERT INTO privileges (name, min_reputation, description) VALUES
+    ('upvote', 0, 'Votar positivamente'),
+    ('comment', 0, 'Comentar em posts de outros usuários'),
+    ('downvote', 125, 'Votar negativamente'),
+    ('create_tags', 300, 'Criar novas tags ao perguntar'),
+    ('edit_posts', 2000, 'Editar perguntas e respostas de outros usuários'),
+    ('close_votes', 3000, 'Votar para fechar e reabrir perguntas')
+ON CONFLICT (name) DO NOTHING;
+
+-- Reações padrão
+INSERT INTO reaction_types (name, emoji, position) VALUES
+    ('like', '👍', 1),
+    ('heart', '❤️', 2),
+    ('laugh', '😂', 3),
+    ('tada', '🎉', 4),
+    ('thinking', '🤔', 5),
+    ('eyes', '👀', 6)
+ON CONFLICT (name) DO NOTHING;
+
+-- Sinônimos padrão
+INSERT INTO tag_synonyms (name, tag_id, status)
+SELECT s.name, t.id, 'approved'
+FROM (VALUES ('golang', 'go'), ('js', 'javascript'), ('py', 'python')) AS s(name, tag)
+JOIN tags t ON t.name = s.tag
+ON CONFLICT DO NOTHING;
@@ -1,12 +1,18 @@
 package handlers
 
 import (
+	"database/sql"
+	"fmt"
 	"msu-forum/database"
 	"msu-forum/models"
+	"msu-forum/realtime"
+	"msu-forum/render"
 	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 // Criar nova resposta
@@ -33,19 +39,33 @@ func CreateAnswer(c *fiber.Ctx) error {
 
 	// Verificar se a pergunta existe
 	var question models.Question
-	err = database.DB.Get(&question, "SELECT id FROM questions WHERE id = $1", questionID)
+	err = database.DB.Get(&question, "SELECT id, user_id, closed_at, locked_at FROM questions WHERE id = $1 AND deleted_at IS NULL", questionID)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Pergunta não encontrada"})
 	}
 
+	// Perguntas fechadas não recebem novas respostas
+	if question.ClosedAt != nil {
+		return c.Status(403).JSON(fiber.Map{"error": "Pergunta fechada não aceita novas respostas"})
+	}
+
+	if question.LockedAt != nil {
+		return c.Status(403).JSON(fiber.Map{"error": "Pergunta trancada não aceita novas respostas"})
+	}
+
+	bodyHTML, err := render.Markdown(data.Body)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Erro ao processar o conteúdo"})
+	}
+
 	// Inserir resposta
-	query := `INSERT INTO answers (question_id, user_id, body, votes, is_accepted, created_at, updated_at) 
-			  VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+	query := `INSERT INTO answers (question_id, user_id, body, body_html, votes, is_accepted, created_at, updated_at) 
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
 
 	var answerID uint64
 	err = database.DB.QueryRow(
 		query,
-		questionID, userID, data.Body, 0, false, now, now,
+		questionID, userID, data.Body, bodyHTML, 0, false, now, now,
 	).Scan(&answerID)
 
 	if err != nil {
@@ -55,10 +75,35 @@ func CreateAnswer(c *fiber.Ctx) error {
 	// Atualizar contador de respostas da pergunta
 	database.DB.Exec("UPDATE questions SET answer_count = answer_count + 1 WHERE id = $1", questionID)
 
-	return c.Status(201).JSON(fiber.Map{"id": answerID, "message": "Resposta criada com sucesso"})
+	clearDraft(userID, "answer", questionID)
+	follow(uint64(userID), "question", questionID)
+	mentions := recordMentions("answer", answerID, userID, data.Body)
+	linkAttachments("answer", answerID, data.Body)
+
+	realtime.Publish(realtime.QuestionTopic(questionID), "answer.created", fiber.Map{
+		"id": answerID, "question_id": questionID, "user_id": userID,
+	})
+
+	dispatchWebhook("answer.created", questionTagNames(questionID), fiber.Map{
+		"id": answerID, "question_id": questionID, "user_id": userID, "url": questionURL(questionID),
+	})
+	queueBadgeCheck(uint64(userID))
+
+	notify(notificationEvent{
+		UserID:     question.UserID,
+		ActorID:    uint64(userID),
+		Type:       "answer",
+		PostType:   "question",
+		PostID:     questionID,
+		QuestionID: questionID,
+	})
+	notifyQuestionFollowers(questionID, question.UserID, uint64(userID))
+
+	return c.Status(201).JSON(fiber.Map{"id": answerID, "message": "Resposta criada com sucesso", "mentions": mentions})
 }
 
 // Listar respostas de uma pergunta
+// (?sort=votes|newest|oldest|active|reputation, pin_accepted=false, filter=highlighted)
 func GetAnswers(c *fiber.Ctx) error {
 	questionID, err := strconv.ParseUint(c.Params("questionId"), 10, 64)
 	if err != nil {
@@ -69,27 +114,143 @@ func GetAnswers(c *fiber.Ctx) error {
 	limit, _ := strconv.Atoi(c.Query("limit", "10"))
 	offset := (page - 1) * limit
 
+	options, err := parseAnswerListOptions(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	userID := c.Locals("user_id").(int)
+	answers, err := selectAnswers(questionID, userID, options, limit, offset)
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar respostas"})
+	}
+
+	if err := attachAnswerComments(answers, userID); err != nil {
+		fmt.Printf("Erro ao buscar comentários: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar comentários"})
+	}
+
+	return c.JSON(answers)
+}
+
+// answerView é a resposta com dados do autor e a prévia dos comentários
+type answerView struct {
+	models.Answer
+	Username       string          `json:"username" db:"username"`
+	AvatarURL      string          `json:"avatar_url" db:"avatar_url"`
+	AuthorRole     *string         `json:"author_role" db:"author_role"`
+	IsHighlighted  bool            `json:"is_highlighted" db:"is_highlighted"`
+	LastActivityAt *time.Time      `json:"last_activity_at" db:"last_activity_at"`
+	ViewerVote     *int16          `json:"viewer_vote" db:"viewer_vote"` // 1, -1 ou null
+	Mentions       []mentionRef    `json:"mentions" db:"-"`
+	Reactions      []reactionCount `json:"reactions" db:"-"`
+	commentThread
+}
+
+// Respostas desses papéis são destacadas na listagem
+var highlightedRoles = []string{"Moderator", "Streamer"}
+
+// Ordenações disponíveis para as respostas
+var answerSorts = map[string]string{
+	"votes":      "a.votes DESC, a.created_at ASC",
+	"newest":     "a.created_at DESC",
+	"oldest":     "a.created_at ASC",
+	"active":     "last_activity_at DESC, a.created_at DESC",
+	"reputation": "u.reputation DESC NULLS LAST, a.votes DESC",
+}
+
+type answerListOptions struct {
+	Sort            string
+	PinAccepted     bool
+	HighlightedOnly bool
+}
+
+func parseAnswerListOptions(c *fiber.Ctx) (answerListOptions, error) {
+	options := answerListOptions{
+		Sort:        c.Query("sort", "votes"),
+		PinAccepted: c.QueryBool("pin_accepted", true),
+	}
+
+	if _, ok := answerSorts[options.Sort]; !ok {
+		return options, fmt.Errorf("Ordenação inválida: %s", options.Sort)
+	}
+
+	switch c.Query("filter") {
+	case "":
+	case "highlighted":
+		options.HighlightedOnly = true
+	default:
+		return options, fmt.Errorf("Filtro inválido: %s", c.Query("filter"))
+	}
+
+	return options, nil
+}
+
+// selectAnswers lista as respostas com o voto de viewerID (0 para visitantes);
+// limit 0 traz todas
+func selectAnswers(questionID uint64, viewerID int, options answerListOptions, limit, offset int) ([]answerView, error) {
+	orderBy := answerSorts[options.Sort]
+	if options.PinAccepted {
+		orderBy = "a.is_accepted DESC, " + orderBy
+	}
+
+	// A última atividade considera edições e comentários
 	query := `
-		SELECT a.*, u.username, u.avatar_url
+		SELECT a.*, u.username, u.avatar_url, u.role AS author_role,
+		       COALESCE(u.role = ANY($3), false) AS is_highlighted,
+		       GREATEST(a.created_at, a.edited_at, (
+		           SELECT MAX(cm.created_at) FROM comments cm
+		           WHERE cm.post_type = 'answer' AND cm.post_id = a.id AND cm.deleted_at IS NULL
+		       )) AS last_activity_at,
+		       v.type AS viewer_vote
 		FROM answers a
 		LEFT JOIN users u ON a.user_id = u.id
-		WHERE a.question_id = $1
-		ORDER BY a.is_accepted DESC, a.votes DESC, a.created_at ASC
-		LIMIT $2 OFFSET $3
-	`
+		LEFT JOIN votes v ON v.post_type = 'answer' AND v.post_id = a.id AND v.user_id = $2
+		WHERE a.question_id = $1 AND a.deleted_at IS NULL
+		  AND (NOT $4 OR u.role = ANY($3))
+		ORDER BY ` + orderBy
+	args := []interface{}{questionID, viewerID, pq.StringArray(highlightedRoles), options.HighlightedOnly}
+
+	if limit > 0 {
+		query += " LIMIT $5 OFFSET $6"
+		args = append(args, limit, offset)
+	}
 
-	var answers []struct {
-		models.Answer
-		Username  string `json:"username" db:"username"`
-		AvatarURL string `json:"avatar_url" db:"avatar_url"`
+	answers := []answerView{}
+	err := database.DB.Select(&answers, query, args...)
+	return answers, err
+}
+
+// attachAnswerComments preenche os comentários, as menções e as reações de cada
+// resposta; viewerID marca as reações do usuário atual (0 para visitantes)
+func attachAnswerComments(answers []answerView, viewerID int) error {
+	ids := make([]uint64, len(answers))
+	for i, answer := range answers {
+		ids[i] = answer.ID
 	}
 
-	err = database.DB.Select(&answers, query, questionID, limit, offset)
+	threads, err := loadCommentThreads("answer", ids)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar respostas"})
+		return err
 	}
 
-	return c.JSON(answers)
+	mentions, err := loadMentions("answer", ids)
+	if err != nil {
+		return err
+	}
+
+	reactions, err := loadReactions("answer", ids, viewerID)
+	if err != nil {
+		return err
+	}
+
+	for i := range answers {
+		answers[i].commentThread = threads[answers[i].ID]
+		answers[i].Mentions = mentions[answers[i].ID]
+		answers[i].Reactions = reactions[answers[i].ID]
+	}
+	return nil
 }
 
 // Atualizar resposta
@@ -115,26 +276,45 @@ func UpdateAnswer(c *fiber.Ctx) error {
 
 	// Verificar se a resposta pertence ao usuário
 	var answer models.Answer
-	err = database.DB.Get(&answer, "SELECT user_id FROM answers WHERE id = $1", id)
+	err = database.DB.Get(&answer, "SELECT user_id FROM answers WHERE id = $1 AND deleted_at IS NULL", id)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Resposta não encontrada"})
 	}
 
 	if uint64(userID) != answer.UserID {
-		return c.Status(403).JSON(fiber.Map{"error": "Sem permissão para editar esta resposta"})
+		ok, _, err := hasPrivilege(c, "edit_posts")
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao verificar privilégios"})
+		}
+		if !ok {
+			return c.Status(403).JSON(fiber.Map{"error": "Sem permissão para editar esta resposta"})
+		}
+	}
+
+	if locked, _ := isPostLocked("answer", id); locked {
+		return c.Status(403).JSON(fiber.Map{"error": "Pergunta trancada não permite edições"})
+	}
+
+	bodyHTML, err := render.Markdown(data.Body)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Erro ao processar o conteúdo"})
 	}
 
 	// Atualizar resposta
 	_, err = database.DB.Exec(
-		"UPDATE answers SET body = $1, updated_at = $2 WHERE id = $3",
-		data.Body, time.Now(), id,
+		"UPDATE answers SET body = $1, body_html = $2, updated_at = $3, edited_at = $3 WHERE id = $4",
+		data.Body, bodyHTML, time.Now(), id,
 	)
 
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar resposta"})
 	}
 
-	return c.JSON(fiber.Map{"message": "Resposta atualizada com sucesso"})
+	clearDraft(userID, "edit_answer", id)
+	mentions := recordMentions("answer", id, int(answer.UserID), data.Body)
+	linkAttachments("answer", id, data.Body)
+
+	return c.JSON(fiber.Map{"message": "Resposta atualizada com sucesso", "mentions": mentions})
 }
 
 // Deletar resposta
@@ -147,30 +327,79 @@ func DeleteAnswer(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(int)
 	role := c.Locals("role").(string)
 
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar resposta"})
+	}
+	defer tx.Rollback()
+
 	// Verificar se a resposta pertence ao usuário ou se é admin
 	var answer models.Answer
-	err = database.DB.Get(&answer, "SELECT user_id, question_id FROM answers WHERE id = $1", id)
-	if err != nil {
+	err = tx.Get(&answer, "SELECT id, user_id, question_id, is_accepted FROM answers WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", id)
+	if err == sql.ErrNoRows {
 		return c.Status(404).JSON(fiber.Map{"error": "Resposta não encontrada"})
 	}
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar resposta"})
+	}
 
 	if uint64(userID) != answer.UserID && role != "Admin" {
 		return c.Status(403).JSON(fiber.Map{"error": "Sem permissão para deletar esta resposta"})
 	}
 
-	// Deletar resposta
-	_, err = database.DB.Exec("DELETE FROM answers WHERE id = $1", id)
+	// A resposta aceita perde o aceite e a reputação concedida por ele
+	if answer.IsAccepted {
+		var question models.Question
+		err = tx.Get(&question, "SELECT id, user_id FROM questions WHERE id = $1 FOR UPDATE", answer.QuestionID)
+		if err != nil {
+			fmt.Printf("Erro no banco: %v\n", err)
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar resposta"})
+		}
+		if err := setAcceptance(tx, answer, question, false); err != nil {
+			fmt.Printf("Erro no banco: %v\n", err)
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar resposta"})
+		}
+	}
+
+	// Exclusão lógica; a remoção definitiva fica com o job de retenção
+	_, err = tx.Exec(
+		"UPDATE answers SET deleted_at = $1, deleted_by = $2 WHERE id = $3",
+		time.Now(), userID, id,
+	)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar resposta"})
 	}
 
 	// Atualizar contador de respostas da pergunta
-	database.DB.Exec("UPDATE questions SET answer_count = answer_count - 1 WHERE id = $1", answer.QuestionID)
+	_, err = tx.Exec("UPDATE questions SET answer_count = answer_count - 1 WHERE id = $1", answer.QuestionID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar resposta"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar resposta"})
+	}
+
+	if answer.IsAccepted {
+		realtime.Publish(realtime.QuestionTopic(answer.QuestionID), "answer.unaccepted", fiber.Map{
+			"id": id, "question_id": answer.QuestionID,
+		})
+	}
 
 	return c.JSON(fiber.Map{"message": "Resposta deletada com sucesso"})
 }
 
-// Aceitar resposta
+// Reputação ganha com respostas aceitas; aceitar a própria resposta não rende pontos
+const (
+	acceptedAnswerReputation = 15
+	acceptorReputation       = 2
+)
+
+// Prazo antes de o autor poder aceitar a própria resposta, para dar chance a outras
+const selfAcceptDelay = 48 * time.Hour
+
+// Aceitar resposta (substitui a resposta aceita anteriormente)
 func AcceptAnswer(c *fiber.Ctx) error {
 	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
 	if err != nil {
@@ -179,16 +408,22 @@ func AcceptAnswer(c *fiber.Ctx) error {
 
 	userID := c.Locals("user_id").(int)
 
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao aceitar resposta"})
+	}
+	defer tx.Rollback()
+
 	// Verificar se a resposta existe e buscar a pergunta
 	var answer models.Answer
-	err = database.DB.Get(&answer, "SELECT question_id FROM answers WHERE id = $1", id)
+	err = tx.Get(&answer, "SELECT id, user_id, question_id, is_accepted FROM answers WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", id)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Resposta não encontrada"})
 	}
 
-	// Verificar se o usuário é o dono da pergunta
+	// Verificar se o usuário é o dono da pergunta; o bloqueio serializa aceites concorrentes
 	var question models.Question
-	err = database.DB.Get(&question, "SELECT user_id FROM questions WHERE id = $1", answer.QuestionID)
+	err = tx.Get(&question, "SELECT id, user_id, created_at FROM questions WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", answer.QuestionID)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Pergunta não encontrada"})
 	}
@@ -197,17 +432,146 @@ func AcceptAnswer(c *fiber.Ctx) error {
 		return c.Status(403).JSON(fiber.Map{"error": "Apenas o autor da pergunta pode aceitar respostas"})
 	}
 
-	// Desmarcar outras respostas como aceitas
-	database.DB.Exec("UPDATE answers SET is_accepted = false WHERE question_id = $1", answer.QuestionID)
+	if answer.IsAccepted {
+		return c.Status(409).JSON(fiber.Map{"error": "Resposta já está aceita"})
+	}
+
+	if answer.UserID == question.UserID && time.Since(question.CreatedAt) < selfAcceptDelay {
+		return c.Status(403).JSON(fiber.Map{
+			"error":        "A própria resposta só pode ser aceita 48 horas após a pergunta",
+			"available_at": question.CreatedAt.Add(selfAcceptDelay),
+		})
+	}
 
-	// Marcar esta resposta como aceita
-	_, err = database.DB.Exec("UPDATE answers SET is_accepted = true WHERE id = $1", id)
+	// Desfazer o aceite anterior, revertendo a reputação concedida
+	var previous []models.Answer
+	err = tx.Select(&previous, "SELECT id, user_id, question_id FROM answers WHERE question_id = $1 AND is_accepted = true", answer.QuestionID)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao aceitar resposta"})
 	}
+	for _, prev := range previous {
+		if err := setAcceptance(tx, prev, question, false); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao aceitar resposta"})
+		}
+	}
+
+	if err := setAcceptance(tx, answer, question, true); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao aceitar resposta"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao aceitar resposta"})
+	}
+
+	for _, prev := range previous {
+		realtime.Publish(realtime.QuestionTopic(answer.QuestionID), "answer.unaccepted", fiber.Map{
+			"id": prev.ID, "question_id": answer.QuestionID,
+		})
+	}
 
-	// Marcar pergunta como resolvida
-	database.DB.Exec("UPDATE questions SET is_solved = true WHERE id = $1", answer.QuestionID)
+	realtime.Publish(realtime.QuestionTopic(answer.QuestionID), "answer.accepted", fiber.Map{
+		"id": id, "question_id": answer.QuestionID,
+	})
+
+	dispatchWebhook("answer.accepted", questionTagNames(answer.QuestionID), fiber.Map{
+		"id": id, "question_id": answer.QuestionID, "user_id": answer.UserID, "url": questionURL(answer.QuestionID),
+	})
+	queueBadgeCheck(answer.UserID)
+
+	notify(notificationEvent{
+		UserID:     answer.UserID,
+		ActorID:    uint64(userID),
+		Type:       "accepted",
+		PostType:   "answer",
+		PostID:     id,
+		QuestionID: answer.QuestionID,
+	})
 
 	return c.JSON(fiber.Map{"message": "Resposta aceita com sucesso"})
 }
+
+// Desfazer o aceite de uma resposta
+func UnacceptAnswer(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	userID := c.Locals("user_id").(int)
+
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao desfazer aceite"})
+	}
+	defer tx.Rollback()
+
+	var answer models.Answer
+	err = tx.Get(&answer, "SELECT id, user_id, question_id, is_accepted FROM answers WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Resposta não encontrada"})
+	}
+
+	var question models.Question
+	err = tx.Get(&question, "SELECT id, user_id, created_at FROM questions WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", answer.QuestionID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Pergunta não encontrada"})
+	}
+
+	if uint64(userID) != question.UserID {
+		return c.Status(403).JSON(fiber.Map{"error": "Apenas o autor da pergunta pode desfazer o aceite"})
+	}
+
+	if !answer.IsAccepted {
+		return c.Status(409).JSON(fiber.Map{"error": "Resposta não está aceita"})
+	}
+
+	if err := setAcceptance(tx, answer, question, false); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao desfazer aceite"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao desfazer aceite"})
+	}
+
+	realtime.Publish(realtime.QuestionTopic(answer.QuestionID), "answer.unaccepted", fiber.Map{
+		"id": id, "question_id": answer.QuestionID,
+	})
+
+	return c.JSON(fiber.Map{"message": "Aceite desfeito com sucesso"})
+}
+
+// setAcceptance marca ou desmarca a resposta como aceita, ajusta a reputação do
+// autor da resposta e de quem aceitou, e recalcula is_solved da pergunta
+func setAcceptance(tx *sqlx.Tx, answer models.Answer, question models.Question, accepted bool) error {
+	_, err := tx.Exec(
+		"UPDATE answers SET is_accepted = $1, accepted_at = CASE WHEN $1 THEN $2::timestamp END WHERE id = $3",
+		accepted, time.Now(), answer.ID)
+	if err != nil {
+		return err
+	}
+
+	if answer.UserID != question.UserID {
+		sign, answererReason, acceptorReason := 1, "accepted", "accept_given"
+		if !accepted {
+			sign, answererReason, acceptorReason = -1, "accept_reversal", "accept_reversal"
+		}
+
+		changes := []reputationChange{
+			{UserID: answer.UserID, Delta: sign * acceptedAnswerReputation, Reason: answererReason},
+			{UserID: question.UserID, Delta: sign * acceptorReputation, Reason: acceptorReason},
+		}
+		for _, change := range changes {
+			change.PostType, change.PostID, change.ActorID = "answer", answer.ID, question.UserID
+			if err := addReputation(tx, change); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE questions
+		SET is_solved = EXISTS(SELECT 1 FROM answers WHERE question_id = $1 AND is_accepted = true AND deleted_at IS NULL)
+		WHERE id = $1
+	`, question.ID)
+	return err
+}
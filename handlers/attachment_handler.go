@@ -0,0 +1,456 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"msu-forum/database"
+	"msu-forum/models"
+	"msu-forum/storage"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/disintegration/imaging"
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gofiber/fiber/v2"
+	"github.com/lib/pq"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	attachmentMaxSize     = 8 << 20
+	attachmentMaxPixels   = 40_000_000
+	attachmentMaxFrames   = 300
+	attachmentThumbSize   = 320
+	orphanAttachmentGrace = 24 * time.Hour
+)
+
+// Tipos aceitos (detectados pelo conteúdo, não pela extensão) e a extensão gravada
+var attachmentTypes = []struct {
+	mime string
+	ext  string
+}{
+	{"image/jpeg", ".jpg"},
+	{"image/png", ".png"},
+	{"image/gif", ".gif"},
+	{"image/webp", ".png"}, // reencodada como PNG
+	{"application/pdf", ".pdf"},
+	{"application/zip", ".zip"},
+	{"text/plain", ".txt"},
+}
+
+// Anexos são referenciados no corpo dos posts pela URL, que contém o hash
+var attachmentRefPattern = regexp.MustCompile(`\b([a-f0-9]{64})(?:_thumb)?\.[a-z]+\b`)
+
+// attachmentView é o anexo com as URLs públicas e o trecho Markdown pronto para colar
+type attachmentView struct {
+	models.Attachment
+	URL          string  `json:"url"`
+	ThumbnailURL *string `json:"thumbnail_url"`
+	Markdown     string  `json:"markdown"`
+}
+
+// Enviar anexo (multipart, campo "file")
+func UploadAttachment(c *fiber.Ctx) error {
+	header, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Arquivo não enviado"})
+	}
+
+	if header.Size > attachmentMaxSize {
+		return c.Status(413).JSON(fiber.Map{"error": fmt.Sprintf("Arquivo excede o limite de %d MB", attachmentMaxSize>>20)})
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Erro ao ler arquivo"})
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, attachmentMaxSize+1))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Erro ao ler arquivo"})
+	}
+	if len(data) > attachmentMaxSize {
+		return c.Status(413).JSON(fiber.Map{"error": fmt.Sprintf("Arquivo excede o limite de %d MB", attachmentMaxSize>>20)})
+	}
+
+	detected := mimetype.Detect(data)
+	mimeType, ext := "", ""
+	for _, t := range attachmentTypes {
+		if detected.Is(t.mime) {
+			mimeType, ext = t.mime, t.ext
+			break
+		}
+	}
+	if mimeType == "" {
+		return c.Status(415).JSON(fiber.Map{"error": "Tipo de arquivo não permitido: " + detected.String()})
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	userID := c.Locals("user_id").(int)
+	now := time.Now()
+
+	// Conteúdo já enviado: reaproveita o arquivo e adia a limpeza de órfãos
+	var attachment models.Attachment
+	err = database.DB.Get(&attachment, "UPDATE attachments SET updated_at = $1 WHERE hash = $2 RETURNING *", now, hash)
+	if err == nil {
+		return c.JSON(newAttachmentView(attachment))
+	}
+
+	attachment = models.Attachment{
+		Hash:       hash,
+		StorageKey: hash + ext,
+		Filename:   sanitizeFilename(header.Filename),
+		MimeType:   mimeType,
+		Size:       int64(len(data)),
+	}
+
+	if strings.HasPrefix(mimeType, "image/") {
+		processed, err := processImage(data, mimeType)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		thumbKey := hash + "_thumb" + ext
+		if err := storage.Default.Put(thumbKey, bytes.NewReader(processed.thumbnail)); err != nil {
+			fmt.Printf("Erro ao gravar miniatura: %v\n", err)
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao salvar arquivo"})
+		}
+
+		data = processed.data
+		attachment.ThumbnailKey = &thumbKey
+		attachment.Size = int64(len(data))
+		attachment.Width = &processed.width
+		attachment.Height = &processed.height
+		if mimeType == "image/webp" {
+			attachment.MimeType = "image/png"
+		}
+	}
+
+	if err := storage.Default.Put(attachment.StorageKey, bytes.NewReader(data)); err != nil {
+		fmt.Printf("Erro ao gravar anexo: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao salvar arquivo"})
+	}
+
+	// Envios simultâneos do mesmo conteúdo convergem para o mesmo registro
+	err = database.DB.Get(&attachment, `
+		INSERT INTO attachments (user_id, hash, storage_key, thumbnail_key, filename, mime_type, size, width, height, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10)
+		ON CONFLICT (hash) DO UPDATE SET updated_at = EXCLUDED.updated_at
+		RETURNING *
+	`, userID, attachment.Hash, attachment.StorageKey, attachment.ThumbnailKey, attachment.Filename,
+		attachment.MimeType, attachment.Size, attachment.Width, attachment.Height, now)
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao salvar arquivo"})
+	}
+
+	return c.Status(201).JSON(newAttachmentView(attachment))
+}
+
+// Dados de um anexo
+func GetAttachment(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	var attachment models.Attachment
+	if err := database.DB.Get(&attachment, "SELECT * FROM attachments WHERE id = $1", id); err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Anexo não encontrado"})
+	}
+
+	return c.JSON(newAttachmentView(attachment))
+}
+
+// Deletar anexo próprio que não é usado em nenhum post
+func DeleteAttachment(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	userID := c.Locals("user_id").(int)
+
+	var attachment models.Attachment
+	err = database.DB.Get(&attachment, "SELECT * FROM attachments WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Anexo não encontrado"})
+	}
+
+	result, err := database.DB.Exec(`
+		DELETE FROM attachments a
+		WHERE a.id = $1 AND NOT EXISTS (SELECT 1 FROM post_attachments pa WHERE pa.attachment_id = a.id)
+	`, id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar anexo"})
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return c.Status(409).JSON(fiber.Map{"error": "Anexo em uso por um post"})
+	}
+
+	removeAttachmentFiles(attachment)
+
+	return c.JSON(fiber.Map{"message": "Anexo deletado com sucesso"})
+}
+
+// linkAttachments registra os anexos citados no corpo do post, substituindo os anteriores
+func linkAttachments(postType string, postID uint64, body string) {
+	hashes := []string{}
+	for _, match := range attachmentRefPattern.FindAllStringSubmatch(body, -1) {
+		hashes = append(hashes, match[1])
+	}
+
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		fmt.Printf("Erro ao vincular anexos: %v\n", err)
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM post_attachments WHERE post_type = $1 AND post_id = $2", postType, postID)
+	if err == nil && len(hashes) > 0 {
+		_, err = tx.Exec(`
+			INSERT INTO post_attachments (attachment_id, post_type, post_id)
+			SELECT id, $1, $2 FROM attachments WHERE hash = ANY($3)
+			ON CONFLICT DO NOTHING
+		`, postType, postID, pq.StringArray(hashes))
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		fmt.Printf("Erro ao vincular anexos do %s %d: %v\n", postType, postID, err)
+	}
+}
+
+// PurgeOrphanAttachments remove anexos que nenhum post, reação ou rascunho referencia após o prazo
+// de carência. Executado pelo agendador.
+func PurgeOrphanAttachments() error {
+	var orphans []models.Attachment
+	err := database.DB.Select(&orphans, `
+		DELETE FROM attachments a
+		WHERE a.updated_at < $1
+		  AND NOT EXISTS (SELECT 1 FROM post_attachments pa WHERE pa.attachment_id = a.id)
+		  AND NOT EXISTS (SELECT 1 FROM reaction_types rt WHERE rt.image_url LIKE '%' || a.hash || '%')
+		  AND NOT EXISTS (SELECT 1 FROM drafts d WHERE d.body LIKE '%' || a.hash || '%')
+		RETURNING *
+	`, time.Now().Add(-orphanAttachmentGrace))
+	if err != nil {
+		return err
+	}
+
+	for _, attachment := range orphans {
+		removeAttachmentFiles(attachment)
+	}
+	return nil
+}
+
+func removeAttachmentFiles(attachment models.Attachment) {
+	keys := []string{attachment.StorageKey}
+	if attachment.ThumbnailKey != nil {
+		keys = append(keys, *attachment.ThumbnailKey)
+	}
+
+	for _, key := range keys {
+		if err := storage.Default.Delete(key); err != nil {
+			fmt.Printf("Erro ao remover arquivo %s: %v\n", key, err)
+		}
+	}
+}
+
+type processedImage struct {
+	data      []byte
+	thumbnail []byte
+	width     int32
+	height    int32
+}
+
+// processImage reencoda a imagem, descartando EXIF e outros metadados, e gera a miniatura.
+// A orientação do EXIF é aplicada antes de ser descartada.
+func processImage(data []byte, mimeType string) (processedImage, error) {
+	var result processedImage
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return result, fmt.Errorf("Imagem inválida")
+	}
+	if config.Width*config.Height > attachmentMaxPixels {
+		return result, fmt.Errorf("Imagem com resolução muito alta")
+	}
+
+	var out bytes.Buffer
+	var img image.Image
+
+	// GIFs animados mantêm os quadros; o formato não carrega EXIF. Animações grandes
+	// demais para decodificar inteiras são guardadas como enviadas, e a miniatura
+	// usa só o primeiro quadro.
+	if mimeType == "image/gif" {
+		frames, err := gifFrameCount(data)
+		if err != nil {
+			return result, fmt.Errorf("Imagem inválida")
+		}
+
+		if frames > attachmentMaxFrames || frames*config.Width*config.Height > attachmentMaxPixels {
+			img, err = gif.Decode(bytes.NewReader(data))
+			if err != nil {
+				return result, fmt.Errorf("Imagem inválida")
+			}
+			return finishImage(data, img, mimeType)
+		}
+
+		animation, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return result, fmt.Errorf("Imagem inválida")
+		}
+		if err := gif.EncodeAll(&out, animation); err != nil {
+			return result, err
+		}
+		img = animation.Image[0]
+	} else {
+		img, err = imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+		if err != nil {
+			return result, fmt.Errorf("Imagem inválida")
+		}
+		if err := encodeImage(&out, img, mimeType); err != nil {
+			return result, err
+		}
+	}
+
+	return finishImage(out.Bytes(), img, mimeType)
+}
+
+// finishImage gera a miniatura e preenche o resultado com a imagem final
+func finishImage(data []byte, img image.Image, mimeType string) (processedImage, error) {
+	var result processedImage
+
+	var thumb bytes.Buffer
+	thumbnail := img
+	if img.Bounds().Dx() > attachmentThumbSize || img.Bounds().Dy() > attachmentThumbSize {
+		thumbnail = imaging.Fit(img, attachmentThumbSize, attachmentThumbSize, imaging.Lanczos)
+	}
+	if err := encodeImage(&thumb, thumbnail, mimeType); err != nil {
+		return result, err
+	}
+
+	result.data = data
+	result.thumbnail = thumb.Bytes()
+	result.width = int32(img.Bounds().Dx())
+	result.height = int32(img.Bounds().Dy())
+	return result, nil
+}
+
+// gifFrameCount conta os quadros percorrendo os blocos do GIF, sem decodificar os pixels
+func gifFrameCount(data []byte) (int, error) {
+	invalid := fmt.Errorf("GIF inválido")
+	if len(data) < 13 {
+		return 0, invalid
+	}
+
+	pos := 13
+	// Tabela de cores global
+	if data[10]&0x80 != 0 {
+		pos += 3 << (data[10]&0x07 + 1)
+	}
+
+	// skipSubBlocks avança sobre uma sequência de sub-blocos terminada por tamanho 0
+	skipSubBlocks := func() bool {
+		for pos < len(data) {
+			size := int(data[pos])
+			pos++
+			if size == 0 {
+				return true
+			}
+			pos += size
+		}
+		return false
+	}
+
+	frames := 0
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x21: // Extensão: rótulo seguido de sub-blocos
+			pos += 2
+			if !skipSubBlocks() {
+				return 0, invalid
+			}
+		case 0x2C: // Descritor de imagem
+			if pos+10 > len(data) {
+				return 0, invalid
+			}
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&0x07 + 1)
+			}
+			// Tamanho mínimo do código LZW e os dados da imagem
+			pos++
+			if !skipSubBlocks() {
+				return 0, invalid
+			}
+			frames++
+		case 0x3B: // Fim do arquivo
+			return frames, nil
+		default:
+			return 0, invalid
+		}
+	}
+
+	return frames, nil
+}
+
+func encodeImage(w io.Writer, img image.Image, mimeType string) error {
+	switch mimeType {
+	case "image/jpeg":
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 90})
+	case "image/gif":
+		return gif.Encode(w, img, nil)
+	default:
+		return png.Encode(w, img)
+	}
+}
+
+func newAttachmentView(attachment models.Attachment) attachmentView {
+	view := attachmentView{Attachment: attachment, URL: storage.Default.URL(attachment.StorageKey)}
+	if attachment.ThumbnailKey != nil {
+		thumbnailURL := storage.Default.URL(*attachment.ThumbnailKey)
+		view.ThumbnailURL = &thumbnailURL
+	}
+
+	if strings.HasPrefix(attachment.MimeType, "image/") {
+		view.Markdown = fmt.Sprintf("![%s](%s)", attachment.Filename, view.URL)
+	} else {
+		view.Markdown = fmt.Sprintf("[%s](%s)", attachment.Filename, view.URL)
+	}
+	return view
+}
+
+// sanitizeFilename mantém só o nome base, sem caracteres que quebrem o Markdown
+func sanitizeFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune("[]()<>\"'`\r\n", r) {
+			return -1
+		}
+		return r
+	}, name)
+
+	if name == "" || name == "." || name == "/" {
+		return "arquivo"
+	}
+	if len(name) > 255 {
+		name = name[:255]
+	}
+	return name
+}
@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"fmt"
+	"msu-forum/database"
+	"msu-forum/models"
+	"msu-forum/realtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// badgeRule define uma medalha e a consulta que lista quem a merece. A consulta
+// recebe $1 com o usuário a avaliar (0 avalia todos) e retorna user_id, além de
+// tag_id nas medalhas de tag.
+type badgeRule struct {
+	Slug        string
+	Name        string
+	Description string
+	Tier        string
+	PerTag      bool
+	Query       string
+}
+
+// Pontuação e quantidade de respostas nas perguntas de uma tag
+const tagAnswersQuery = `
+	SELECT a.user_id, qt.tag_id
+	FROM answers a
+	JOIN questions q ON q.id = a.question_id AND q.deleted_at IS NULL
+	JOIN question_tags qt ON qt.question_id = q.id
+	WHERE a.deleted_at IS NULL AND ($1 = 0 OR a.user_id = $1)
+	GROUP BY a.user_id, qt.tag_id
+`
+
+var badgeRules = []badgeRule{
+	{
+		Slug: "professor", Name: "Professor", Tier: "bronze",
+		Description: "Teve uma resposta aceita pela primeira vez",
+		Query: `
+			SELECT DISTINCT a.user_id
+			FROM answers a
+			JOIN questions q ON q.id = a.question_id
+			WHERE a.is_accepted AND a.deleted_at IS NULL AND a.user_id <> q.user_id
+			  AND ($1 = 0 OR a.user_id = $1)
+		`,
+	},
+	{
+		Slug: "aluno", Name: "Aluno", Tier: "bronze",
+		Description: "Fez a primeira pergunta com pontuação positiva",
+		Query: `
+			SELECT DISTINCT user_id FROM questions
+			WHERE votes >= 1 AND deleted_at IS NULL AND ($1 = 0 OR user_id = $1)
+		`,
+	},
+	{
+		Slug: "comentarista", Name: "Comentarista", Tier: "bronze",
+		Description: "Escreveu 10 comentários",
+		Query: `
+			SELECT user_id FROM comments
+			WHERE deleted_at IS NULL AND ($1 = 0 OR user_id = $1)
+			GROUP BY user_id HAVING COUNT(*) >= 10
+		`,
+	},
+	{
+		Slug: "curioso", Name: "Curioso", Tier: "silver",
+		Description: "Fez 10 perguntas com pontuação 5 ou mais",
+		Query: `
+			SELECT user_id FROM questions
+			WHERE votes >= 5 AND deleted_at IS NULL AND ($1 = 0 OR user_id = $1)
+			GROUP BY user_id HAVING COUNT(*) >= 10
+		`,
+	},
+	{
+		Slug: "eleitor", Name: "Eleitor", Tier: "silver",
+		Description: "Votou 300 vezes",
+		Query: `
+			SELECT user_id FROM votes
+			WHERE $1 = 0 OR user_id = $1
+			GROUP BY user_id HAVING COUNT(*) >= 300
+		`,
+	},
+	{
+		Slug: "fanatico", Name: "Fanático", Tier: "gold",
+		Description: "Visitou o fórum em 100 dias diferentes",
+		Query: `
+			SELECT user_id FROM user_visits
+			WHERE $1 = 0 OR user_id = $1
+			GROUP BY user_id HAVING COUNT(*) >= 100
+		`,
+	},
+	{
+		Slug: "tag-bronze", Tier: "bronze", PerTag: true,
+		Description: "Pontuação 10 ou mais em 5 respostas sobre a tag",
+		Query:       tagAnswersQuery + `HAVING SUM(a.votes) >= 10 AND COUNT(*) >= 5`,
+	},
+	{
+		Slug: "tag-silver", Tier: "silver", PerTag: true,
+		Description: "Pontuação 40 ou mais em 10 respostas sobre a tag",
+		Query:       tagAnswersQuery + `HAVING SUM(a.votes) >= 40 AND COUNT(*) >= 10`,
+	},
+	{
+		Slug: "tag-gold", Tier: "gold", PerTag: true,
+		Description: "Pontuação 100 ou mais em 20 respostas sobre a tag",
+		Query:       tagAnswersQuery + `HAVING SUM(a.votes) >= 100 AND COUNT(*) >= 20`,
+	},
+}
+
+// Usuários com atividade recente aguardando avaliação; o tamanho do buffer limita
+// a fila e o que não couber fica para a varredura periódica
+var badgeQueue = make(chan uint64, 1024)
+
+// Último dia de visita registrado por usuário, para gravar uma vez por dia
+var recordedVisits sync.Map
+
+// Listar medalhas com a quantidade de ganhadores (?tier=bronze|silver|gold, ?tag_id=)
+func GetBadges(c *fiber.Ctx) error {
+	tagID, _ := strconv.ParseUint(c.Query("tag_id", "0"), 10, 64)
+
+	badges := []struct {
+		models.Badge
+		TagName    *string `json:"tag_name" db:"tag_name"`
+		AwardCount int     `json:"award_count" db:"award_count"`
+	}{}
+	err := database.DB.Select(&badges, `
+		SELECT b.*, t.name AS tag_name, COUNT(ub.user_id) AS award_count
+		FROM badges b
+		LEFT JOIN tags t ON t.id = b.tag_id
+		LEFT JOIN user_badges ub ON ub.badge_id = b.id
+		WHERE ($1 = '' OR b.tier = $1) AND ($2 = 0 OR b.tag_id = $2)
+		GROUP BY b.id, t.name
+		ORDER BY CASE b.tier WHEN 'gold' THEN 0 WHEN 'silver' THEN 1 ELSE 2 END, b.tag_id NULLS FIRST, b.name
+	`, c.Query("tier"), tagID)
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar medalhas"})
+	}
+
+	return c.JSON(badges)
+}
+
+// Listar quem conquistou uma medalha, das conquistas mais recentes para as antigas
+func GetBadgeHolders(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	offset := (page - 1) * limit
+
+	var badge models.Badge
+	if err := database.DB.Get(&badge, "SELECT * FROM badges WHERE id = $1", id); err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Medalha não encontrada"})
+	}
+
+	holders := []struct {
+		UserID    uint64    `json:"user_id" db:"user_id"`
+		Username  string    `json:"username" db:"username"`
+		AvatarURL *string   `json:"avatar_url" db:"avatar_url"`
+		AwardedAt time.Time `json:"awarded_at" db:"awarded_at"`
+	}{}
+	err = database.DB.Select(&holders, `
+		SELECT ub.user_id, u.username, u.avatar_url, ub.awarded_at
+		FROM user_badges ub
+		JOIN users u ON u.id = ub.user_id
+		WHERE ub.badge_id = $1
+		ORDER BY ub.awarded_at DESC
+		LIMIT $2 OFFSET $3
+	`, id, limit, offset)
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar ganhadores"})
+	}
+
+	return c.JSON(fiber.Map{"badge": badge, "holders": holders})
+}
+
+// Listar as medalhas de um usuário, com o total por nível
+func GetUserBadges(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	badges := []struct {
+		models.Badge
+		TagName   *string   `json:"tag_name" db:"tag_name"`
+		AwardedAt time.Time `json:"awarded_at" db:"awarded_at"`
+	}{}
+	err = database.DB.Select(&badges, `
+		SELECT b.*, t.name AS tag_name, ub.awarded_at
+		FROM user_badges ub
+		JOIN badges b ON b.id = ub.badge_id
+		LEFT JOIN tags t ON t.id = b.tag_id
+		WHERE ub.user_id = $1
+		ORDER BY ub.awarded_at DESC
+	`, id)
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar medalhas"})
+	}
+
+	counts := fiber.Map{"bronze": 0, "silver": 0, "gold": 0}
+	for _, badge := range badges {
+		counts[badge.Tier] = counts[badge.Tier].(int) + 1
+	}
+
+	return c.JSON(fiber.Map{"badges": badges, "counts": counts})
+}
+
+// RecordVisit registra o dia de acesso do usuário autenticado (usado pelas medalhas
+// de frequência). Middleware das rotas protegidas.
+func RecordVisit(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(int)
+	if !ok {
+		return c.Next()
+	}
+
+	today := time.Now().Format("2006-01-02")
+	if last, _ := recordedVisits.Load(userID); last != today {
+		_, err := database.DB.Exec("INSERT INTO user_visits (user_id, day) VALUES ($1, $2) ON CONFLICT DO NOTHING", userID, today)
+		if err != nil {
+			fmt.Printf("Erro ao registrar visita do usuário ID %d: %v\n", userID, err)
+		} else {
+			recordedVisits.Store(userID, today)
+			queueBadgeCheck(uint64(userID))
+		}
+	}
+
+	return c.Next()
+}
+
+// SyncBadges grava no banco as medalhas definidas em badgeRules. As medalhas de
+// tag são criadas pelo avaliador quando alguém as conquista.
+func SyncBadges() error {
+	for _, rule := range badgeRules {
+		if rule.PerTag {
+			continue
+		}
+		_, err := database.DB.Exec(`
+			INSERT INTO badges (slug, name, description, tier, created_at)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (slug) WHERE tag_id IS NULL
+			DO UPDATE SET name = EXCLUDED.name, description = EXCLUDED.description, tier = EXCLUDED.tier
+		`, rule.Slug, rule.Name, rule.Description, rule.Tier, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartBadgeEvaluator avalia em segundo plano os usuários enfileirados pelos
+// eventos (voto, resposta aceita, novo post, visita)
+func StartBadgeEvaluator() {
+	go func() {
+		for userID := range badgeQueue {
+			if err := evaluateBadges(userID); err != nil {
+				fmt.Printf("Erro ao avaliar medalhas do usuário ID %d: %v\n", userID, err)
+			}
+		}
+	}()
+}
+
+// EvaluateBadges avalia todos os usuários, cobrindo eventos que não couberam na
+// fila e regras que dependem do tempo. Executado pelo agendador.
+func EvaluateBadges() error {
+	return evaluateBadges(0)
+}
+
+// queueBadgeCheck enfileira usuários para avaliação sem bloquear a requisição
+func queueBadgeCheck(userIDs ...uint64) {
+	for _, userID := range userIDs {
+		select {
+		case badgeQueue <- userID:
+		default:
+		}
+	}
+}
+
+// evaluateBadges concede as medalhas que o usuário (0 para todos) passou a merecer
+func evaluateBadges(userID uint64) error {
+	now := time.Now()
+
+	for _, rule := range badgeRules {
+		var awarded []models.UserBadge
+		var err error
+
+		if rule.PerTag {
+			// Cria as medalhas das tags que ganharam o primeiro merecedor
+			_, err = database.DB.Exec(`
+				WITH qualified AS (`+rule.Query+`)
+				INSERT INTO badges (slug, name, description, tier, tag_id, created_at)
+				SELECT DISTINCT $2, t.name, $3, $4, t.id, $5::timestamp
+				FROM qualified q
+				JOIN tags t ON t.id = q.tag_id
+				ON CONFLICT (slug, tag_id) WHERE tag_id IS NOT NULL DO NOTHING
+			`, userID, rule.Slug, rule.Description, rule.Tier, now)
+			if err != nil {
+				return err
+			}
+
+			err = database.DB.Select(&awarded, `
+				WITH qualified AS (`+rule.Query+`)
+				INSERT INTO user_badges (user_id, badge_id, awarded_at)
+				SELECT q.user_id, b.id, $3::timestamp
+				FROM qualified q
+				JOIN badges b ON b.slug = $2 AND b.tag_id = q.tag_id
+				ON CONFLICT DO NOTHING
+				RETURNING *
+			`, userID, rule.Slug, now)
+		} else {
+			err = database.DB.Select(&awarded, `
+				WITH qualified AS (`+rule.Query+`)
+				INSERT INTO user_badges (user_id, badge_id, awarded_at)
+				SELECT q.user_id, b.id, $3::timestamp
+				FROM qualified q
+				JOIN badges b ON b.slug = $2 AND b.tag_id IS NULL
+				ON CONFLICT DO NOTHING
+				RETURNING *
+			`, userID, rule.Slug, now)
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, award := range awarded {
+			realtime.Publish(realtime.UserTopic(award.UserID), "badge.awarded", fiber.Map{
+				"badge_id": award.BadgeID, "slug": rule.Slug, "tier": rule.Tier, "awarded_at": award.AwardedAt,
+			})
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,461 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"msu-forum/database"
+	"msu-forum/models"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jmoiron/sqlx"
+)
+
+type collectionInput struct {
+	Name        string `json:"name" validate:"required,min=1,max=100"`
+	Description string `json:"description" validate:"max=500"`
+	IsPublic    bool   `json:"is_public"`
+}
+
+// collectionView é a coleção com a quantidade de itens e o link de compartilhamento
+type collectionView struct {
+	models.BookmarkCollection
+	ItemCount int    `json:"item_count" db:"item_count"`
+	ShareURL  string `json:"share_url,omitempty" db:"-"`
+}
+
+// bookmarkView é o favorito com o título e o trecho do post salvo
+type bookmarkView struct {
+	models.Bookmark
+	QuestionID uint64 `json:"question_id" db:"question_id"`
+	Title      string `json:"title" db:"title"`
+	Excerpt    string `json:"excerpt" db:"excerpt"`
+}
+
+// Listar coleções do usuário
+func GetCollections(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	collections := []collectionView{}
+	err := database.DB.Select(&collections, `
+		SELECT bc.*, COUNT(b.id) AS item_count
+		FROM bookmark_collections bc
+		LEFT JOIN bookmarks b ON b.collection_id = bc.id
+		WHERE bc.user_id = $1
+		GROUP BY bc.id
+		ORDER BY bc.name
+	`, userID)
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar coleções"})
+	}
+
+	for i := range collections {
+		collections[i].ShareURL = collectionShareURL(collections[i].BookmarkCollection)
+	}
+
+	return c.JSON(collections)
+}
+
+// Criar coleção
+func CreateCollection(c *fiber.Ctx) error {
+	var data collectionInput
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "JSON inválido"})
+	}
+
+	data.Name = strings.TrimSpace(data.Name)
+	if err := Validate.Struct(data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos"})
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar coleção"})
+	}
+
+	userID := c.Locals("user_id").(int)
+	now := time.Now()
+
+	var collection models.BookmarkCollection
+	err := database.DB.Get(&collection, `
+		INSERT INTO bookmark_collections (user_id, name, description, is_public, share_token, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+		ON CONFLICT (user_id, name) DO NOTHING
+		RETURNING *
+	`, userID, data.Name, data.Description, data.IsPublic, hex.EncodeToString(token), now)
+	if err == sql.ErrNoRows {
+		return c.Status(409).JSON(fiber.Map{"error": "Já existe uma coleção com esse nome"})
+	}
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar coleção"})
+	}
+
+	return c.Status(201).JSON(collectionView{BookmarkCollection: collection, ShareURL: collectionShareURL(collection)})
+}
+
+// Atualizar coleção
+func UpdateCollection(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	var data collectionInput
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "JSON inválido"})
+	}
+
+	data.Name = strings.TrimSpace(data.Name)
+	if err := Validate.Struct(data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos"})
+	}
+
+	userID := c.Locals("user_id").(int)
+
+	var duplicate bool
+	database.DB.Get(&duplicate, "SELECT EXISTS(SELECT 1 FROM bookmark_collections WHERE user_id = $1 AND name = $2 AND id <> $3)", userID, data.Name, id)
+	if duplicate {
+		return c.Status(409).JSON(fiber.Map{"error": "Já existe uma coleção com esse nome"})
+	}
+
+	var collection models.BookmarkCollection
+	err = database.DB.Get(&collection, `
+		UPDATE bookmark_collections SET name = $1, description = $2, is_public = $3, updated_at = $4
+		WHERE id = $5 AND user_id = $6
+		RETURNING *
+	`, data.Name, data.Description, data.IsPublic, time.Now(), id, userID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Coleção não encontrada"})
+	}
+
+	return c.JSON(collectionView{BookmarkCollection: collection, ShareURL: collectionShareURL(collection)})
+}
+
+// Deletar coleção e seus favoritos
+func DeleteCollection(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	userID := c.Locals("user_id").(int)
+
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar coleção"})
+	}
+	defer tx.Rollback()
+
+	// Guardar os posts antes do cascade para recalcular os contadores
+	var posts []models.Bookmark
+	err = tx.Select(&posts, `
+		SELECT b.post_type, b.post_id FROM bookmarks b
+		JOIN bookmark_collections bc ON bc.id = b.collection_id
+		WHERE bc.id = $1 AND bc.user_id = $2
+	`, id, userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar coleção"})
+	}
+
+	result, err := tx.Exec("DELETE FROM bookmark_collections WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar coleção"})
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Coleção não encontrada"})
+	}
+
+	for _, post := range posts {
+		if err := refreshBookmarkCount(tx, post.PostType, post.PostID); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar coleção"})
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar coleção"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Coleção deletada com sucesso"})
+}
+
+// Itens de uma coleção do usuário
+func GetCollection(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	userID := c.Locals("user_id").(int)
+
+	var collection models.BookmarkCollection
+	err = database.DB.Get(&collection, "SELECT * FROM bookmark_collections WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Coleção não encontrada"})
+	}
+
+	return collectionResponse(c, collection, true)
+}
+
+// Coleção pública compartilhada por link
+func GetSharedCollection(c *fiber.Ctx) error {
+	var collection models.BookmarkCollection
+	err := database.DB.Get(&collection, "SELECT * FROM bookmark_collections WHERE share_token = $1 AND is_public = true", c.Params("token"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Coleção não encontrada"})
+	}
+
+	return collectionResponse(c, collection, false)
+}
+
+// Adicionar post à coleção
+func AddBookmark(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	var data struct {
+		PostType string `json:"post_type" validate:"required,oneof=question answer"`
+		PostID   uint64 `json:"post_id" validate:"required"`
+		Note     string `json:"note" validate:"max=1000"`
+	}
+
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "JSON inválido"})
+	}
+
+	if err := Validate.Struct(data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos"})
+	}
+
+	userID := c.Locals("user_id").(int)
+
+	var owns bool
+	database.DB.Get(&owns, "SELECT EXISTS(SELECT 1 FROM bookmark_collections WHERE id = $1 AND user_id = $2)", id, userID)
+	if !owns {
+		return c.Status(404).JSON(fiber.Map{"error": "Coleção não encontrada"})
+	}
+
+	if _, err := isPostLocked(data.PostType, data.PostID); err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Post não encontrado"})
+	}
+
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao salvar favorito"})
+	}
+	defer tx.Rollback()
+
+	// Novos itens vão para o fim da coleção
+	var bookmark models.Bookmark
+	err = tx.Get(&bookmark, `
+		INSERT INTO bookmarks (collection_id, user_id, post_type, post_id, note, position, created_at)
+		VALUES ($1, $2, $3, $4, $5, (SELECT COALESCE(MAX(position), -1) + 1 FROM bookmarks WHERE collection_id = $1), $6)
+		ON CONFLICT (collection_id, post_type, post_id) DO NOTHING
+		RETURNING *
+	`, id, userID, data.PostType, data.PostID, data.Note, time.Now())
+	if err == sql.ErrNoRows {
+		return c.Status(409).JSON(fiber.Map{"error": "Post já está na coleção"})
+	}
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao salvar favorito"})
+	}
+
+	if err := refreshBookmarkCount(tx, data.PostType, data.PostID); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao salvar favorito"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao salvar favorito"})
+	}
+
+	return c.Status(201).JSON(bookmark)
+}
+
+// Atualizar a nota de um favorito
+func UpdateBookmark(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	var data struct {
+		Note string `json:"note" validate:"max=1000"`
+	}
+
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "JSON inválido"})
+	}
+
+	if err := Validate.Struct(data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos"})
+	}
+
+	userID := c.Locals("user_id").(int)
+
+	var bookmark models.Bookmark
+	err = database.DB.Get(&bookmark, "UPDATE bookmarks SET note = $1 WHERE id = $2 AND user_id = $3 RETURNING *", data.Note, id, userID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Favorito não encontrado"})
+	}
+
+	return c.JSON(bookmark)
+}
+
+// Remover favorito
+func DeleteBookmark(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	userID := c.Locals("user_id").(int)
+
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao remover favorito"})
+	}
+	defer tx.Rollback()
+
+	var bookmark models.Bookmark
+	err = tx.Get(&bookmark, "DELETE FROM bookmarks WHERE id = $1 AND user_id = $2 RETURNING *", id, userID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Favorito não encontrado"})
+	}
+
+	if err := refreshBookmarkCount(tx, bookmark.PostType, bookmark.PostID); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao remover favorito"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao remover favorito"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Favorito removido com sucesso"})
+}
+
+// Reordenar os itens de uma coleção; item_ids traz a nova ordem completa
+func ReorderCollection(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	var data struct {
+		ItemIDs []uint64 `json:"item_ids" validate:"required"`
+	}
+
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "JSON inválido"})
+	}
+
+	if err := Validate.Struct(data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos"})
+	}
+
+	userID := c.Locals("user_id").(int)
+
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao reordenar coleção"})
+	}
+	defer tx.Rollback()
+
+	var current []uint64
+	err = tx.Select(&current, `
+		SELECT b.id FROM bookmarks b
+		JOIN bookmark_collections bc ON bc.id = b.collection_id
+		WHERE bc.id = $1 AND bc.user_id = $2
+		FOR UPDATE OF b
+	`, id, userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao reordenar coleção"})
+	}
+
+	// A nova ordem precisa conter exatamente os itens da coleção
+	seen := make(map[uint64]bool, len(current))
+	for _, itemID := range current {
+		seen[itemID] = false
+	}
+	for _, itemID := range data.ItemIDs {
+		if done, ok := seen[itemID]; !ok || done {
+			return c.Status(400).JSON(fiber.Map{"error": "A lista deve conter cada item da coleção uma única vez"})
+		}
+		seen[itemID] = true
+	}
+	if len(data.ItemIDs) != len(current) {
+		return c.Status(400).JSON(fiber.Map{"error": "A lista deve conter cada item da coleção uma única vez"})
+	}
+
+	for position, itemID := range data.ItemIDs {
+		if _, err := tx.Exec("UPDATE bookmarks SET position = $1 WHERE id = $2", position, itemID); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao reordenar coleção"})
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao reordenar coleção"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Coleção reordenada com sucesso"})
+}
+
+// collectionResponse monta a coleção com seus itens; posts excluídos ficam de fora
+func collectionResponse(c *fiber.Ctx, collection models.BookmarkCollection, owner bool) error {
+	items := []bookmarkView{}
+	err := database.DB.Select(&items, `
+		SELECT b.*, q.id AS question_id, q.title,
+		       LEFT(COALESCE(a.body, q.body), 200) AS excerpt
+		FROM bookmarks b
+		LEFT JOIN answers a ON b.post_type = 'answer' AND a.id = b.post_id
+		JOIN questions q ON q.id = CASE WHEN b.post_type = 'answer' THEN a.question_id ELSE b.post_id END
+		WHERE b.collection_id = $1 AND q.deleted_at IS NULL
+		  AND (b.post_type = 'question' OR a.deleted_at IS NULL)
+		ORDER BY b.position, b.id
+	`, collection.ID)
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar favoritos"})
+	}
+
+	view := collectionView{BookmarkCollection: collection, ItemCount: len(items)}
+	if owner {
+		view.ShareURL = collectionShareURL(collection)
+	} else {
+		// Quem abre pelo link não precisa do token
+		view.ShareToken = ""
+	}
+
+	return c.JSON(fiber.Map{"collection": view, "items": items})
+}
+
+// collectionShareURL é o link público da coleção (vazio se for privada)
+func collectionShareURL(collection models.BookmarkCollection) string {
+	if !collection.IsPublic {
+		return ""
+	}
+	return fmt.Sprintf("%s/collections/shared/%s", strings.TrimRight(os.Getenv("APP_URL"), "/"), collection.ShareToken)
+}
+
+// refreshBookmarkCount recalcula quantos usuários salvaram o post
+func refreshBookmarkCount(tx *sqlx.Tx, postType string, postID uint64) error {
+	table := "questions"
+	if postType == "answer" {
+		table = "answers"
+	}
+
+	_, err := tx.Exec(`
+		UPDATE `+table+` SET bookmark_count = (
+			SELECT COUNT(DISTINCT user_id) FROM bookmarks WHERE post_type = $1 AND post_id = $2
+		) WHERE id = $2
+	`, postType, postID)
+	return err
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"msu-forum/database"
+	"msu-forum/models"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	bountyMinAmount = 50
+	bountyMaxAmount = 500
+	bountyDuration  = 7 * 24 * time.Hour
+	// Votos mínimos para a resposta receber a recompensa automaticamente ao expirar
+	bountyAutoAwardMinVotes = 2
+)
+
+// Oferecer recompensa em uma pergunta (apenas o autor)
+func CreateBounty(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	var data struct {
+		Amount int `json:"amount" validate:"required,min=50,max=500"`
+	}
+
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "JSON inválido"})
+	}
+
+	if err := Validate.Struct(data); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("A recompensa deve estar entre %d e %d de reputação", bountyMinAmount, bountyMaxAmount),
+		})
+	}
+
+	userID := c.Locals("user_id").(int)
+
+	var question models.Question
+	err = database.DB.Get(&question, "SELECT id, user_id, closed_at FROM questions WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Pergunta não encontrada"})
+	}
+
+	if uint64(userID) != question.UserID {
+		return c.Status(403).JSON(fiber.Map{"error": "Apenas o autor da pergunta pode oferecer recompensa"})
+	}
+
+	if question.ClosedAt != nil {
+		return c.Status(403).JSON(fiber.Map{"error": "Perguntas fechadas não podem receber recompensa"})
+	}
+
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar recompensa"})
+	}
+	defer tx.Rollback()
+
+	var active bool
+	tx.Get(&active, "SELECT EXISTS(SELECT 1 FROM bounties WHERE question_id = $1 AND status = 'active')", id)
+	if active {
+		return c.Status(409).JSON(fiber.Map{"error": "Pergunta já possui uma recompensa ativa"})
+	}
+
+	// Travar o usuário para evitar gastar a mesma reputação duas vezes
+	var reputation int
+	if err := tx.Get(&reputation, "SELECT reputation FROM users WHERE id = $1 FOR UPDATE", userID); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar recompensa"})
+	}
+
+	if reputation < data.Amount {
+		return c.Status(400).JSON(fiber.Map{"error": "Reputação insuficiente para esta recompensa"})
+	}
+
+	err = addReputation(tx, reputationChange{
+		UserID: uint64(userID), Delta: -data.Amount, Reason: "bounty_offered", PostType: "question", PostID: id, ActorID: uint64(userID),
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar recompensa"})
+	}
+
+	now := time.Now()
+	var bounty models.Bounty
+	err = tx.Get(&bounty, `
+		INSERT INTO bounties (question_id, user_id, amount, status, expires_at, created_at)
+		VALUES ($1, $2, $3, 'active', $4, $5)
+		RETURNING *
+	`, id, userID, data.Amount, now.Add(bountyDuration), now)
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar recompensa"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar recompensa"})
+	}
+
+	return c.Status(201).JSON(bounty)
+}
+
+// Conceder a recompensa ativa a uma resposta (apenas quem ofereceu)
+func AwardBounty(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	var data struct {
+		AnswerID uint64 `json:"answer_id" validate:"required"`
+	}
+
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "JSON inválido"})
+	}
+
+	if err := Validate.Struct(data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos"})
+	}
+
+	userID := c.Locals("user_id").(int)
+
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao conceder recompensa"})
+	}
+	defer tx.Rollback()
+
+	var bounty models.Bounty
+	err = tx.Get(&bounty, "SELECT * FROM bounties WHERE question_id = $1 AND status = 'active' FOR UPDATE", id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Nenhuma recompensa ativa nesta pergunta"})
+	}
+
+	if uint64(userID) != bounty.UserID {
+		return c.Status(403).JSON(fiber.Map{"error": "Apenas quem ofereceu a recompensa pode concedê-la"})
+	}
+
+	var answer models.Answer
+	err = tx.Get(&answer, "SELECT id, user_id, question_id FROM answers WHERE id = $1 AND deleted_at IS NULL", data.AnswerID)
+	if err != nil || answer.QuestionID != bounty.QuestionID {
+		return c.Status(404).JSON(fiber.Map{"error": "Resposta não encontrada nesta pergunta"})
+	}
+
+	if answer.UserID == bounty.UserID {
+		return c.Status(400).JSON(fiber.Map{"error": "Não é possível conceder a recompensa à própria resposta"})
+	}
+
+	if err := awardBounty(tx, bounty, answer); err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao conceder recompensa"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao conceder recompensa"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Recompensa concedida com sucesso", "amount": bounty.Amount})
+}
+
+// Histórico de recompensas de uma pergunta
+func GetQuestionBounties(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	bounties := []models.Bounty{}
+	err = database.DB.Select(&bounties, "SELECT * FROM bounties WHERE question_id = $1 ORDER BY created_at DESC", id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar recompensas"})
+	}
+
+	return c.JSON(bounties)
+}
+
+// Listar perguntas com recompensa ativa (as que expiram primeiro no topo)
+func GetBountiedQuestions(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+	offset := (page - 1) * limit
+
+	query := `
+		SELECT q.*, u.username, u.avatar_url,
+		       b.amount as bounty_amount, b.expires_at as bounty_expires_at
+		FROM questions q
+		JOIN bounties b ON b.question_id = q.id AND b.status = 'active'
+		LEFT JOIN users u ON q.user_id = u.id
+		WHERE q.closed_at IS NULL AND q.deleted_at IS NULL
+		ORDER BY b.expires_at ASC
+		LIMIT $1 OFFSET $2
+	`
+
+	var questions []struct {
+		models.Question
+		Username  string `json:"username" db:"username"`
+		AvatarURL string `json:"avatar_url" db:"avatar_url"`
+	}
+
+	err := database.DB.Select(&questions, query, limit, offset)
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar perguntas"})
+	}
+
+	return c.JSON(questions)
+}
+
+// ExpireBounties encerra as recompensas vencidas, concedendo-as à resposta mais
+// votada (acima do mínimo) ou marcando-as como expiradas. Executado pelo agendador.
+func ExpireBounties() error {
+	var bounties []models.Bounty
+	err := database.DB.Select(&bounties, "SELECT * FROM bounties WHERE status = 'active' AND expires_at <= $1", time.Now())
+	if err != nil {
+		return err
+	}
+
+	// Uma recompensa com erro fica ativa para a próxima execução, sem travar as demais
+	for _, bounty := range bounties {
+		if err := expireBounty(bounty); err != nil {
+			fmt.Printf("Erro ao encerrar recompensa %d: %v\n", bounty.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func expireBounty(bounty models.Bounty) error {
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Revalidar dentro da transação, a recompensa pode ter sido concedida no meio tempo
+	var status string
+	if err := tx.Get(&status, "SELECT status FROM bounties WHERE id = $1 FOR UPDATE", bounty.ID); err != nil {
+		return err
+	}
+	if status != "active" {
+		return nil
+	}
+
+	// Apenas respostas publicadas durante a recompensa e de outros usuários concorrem
+	var answer models.Answer
+	err = tx.Get(&answer, `
+		SELECT id, user_id, question_id FROM answers
+		WHERE question_id = $1 AND user_id <> $2 AND created_at >= $3 AND votes >= $4
+		  AND deleted_at IS NULL
+		ORDER BY votes DESC, created_at ASC
+		LIMIT 1
+	`, bounty.QuestionID, bounty.UserID, bounty.CreatedAt, bountyAutoAwardMinVotes)
+
+	switch {
+	case err == nil:
+		if err := awardBounty(tx, bounty, answer); err != nil {
+			return err
+		}
+	case err == sql.ErrNoRows:
+		if _, err := tx.Exec("UPDATE bounties SET status = 'expired' WHERE id = $1", bounty.ID); err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// awardBounty transfere a recompensa ao autor da resposta
+func awardBounty(tx *sqlx.Tx, bounty models.Bounty, answer models.Answer) error {
+	_, err := tx.Exec(`
+		UPDATE bounties SET status = 'awarded', awarded_answer_id = $1, awarded_at = $2
+		WHERE id = $3
+	`, answer.ID, time.Now(), bounty.ID)
+	if err != nil {
+		return err
+	}
+
+	return addReputation(tx, reputationChange{
+		UserID: answer.UserID, Delta: int(bounty.Amount), Reason: "bounty_awarded", PostType: "answer", PostID: answer.ID, ActorID: bounty.UserID,
+	})
+}
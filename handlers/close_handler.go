@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"fmt"
+	"msu-forum/database"
+	"msu-forum/models"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jmoiron/sqlx"
+)
+
+// Número de votos da comunidade necessários para fechar ou reabrir uma pergunta
+const closeVoteThreshold = 3
+
+// Fechar pergunta (moderadores fecham direto, demais usuários registram voto)
+func CloseQuestion(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	var data struct {
+		Reason      string `json:"reason" validate:"required,oneof=duplicate off-topic unclear resolved-in-game"`
+		DuplicateOf uint64 `json:"duplicate_of"`
+	}
+
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "JSON inválido"})
+	}
+
+	if err := Validate.Struct(data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	userID := c.Locals("user_id").(int)
+	role := c.Locals("role").(string)
+
+	var question models.Question
+	err = database.DB.Get(&question, "SELECT id, closed_at FROM questions WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Pergunta não encontrada"})
+	}
+
+	if question.ClosedAt != nil {
+		return c.Status(409).JSON(fiber.Map{"error": "Pergunta já está fechada"})
+	}
+
+	// Duplicatas precisam apontar para uma pergunta canônica existente
+	var duplicateOf *uint64
+	if data.Reason == "duplicate" {
+		if data.DuplicateOf == 0 || data.DuplicateOf == id {
+			return c.Status(400).JSON(fiber.Map{"error": "duplicate_of deve apontar para outra pergunta"})
+		}
+
+		var exists bool
+		database.DB.Get(&exists, "SELECT EXISTS(SELECT 1 FROM questions WHERE id = $1 AND deleted_at IS NULL)", data.DuplicateOf)
+		if !exists {
+			return c.Status(404).JSON(fiber.Map{"error": "Pergunta original não encontrada"})
+		}
+		duplicateOf = &data.DuplicateOf
+	}
+
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao fechar pergunta"})
+	}
+	defer tx.Rollback()
+
+	ok, required, err := hasPrivilege(c, "close_votes")
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao verificar privilégios"})
+	}
+	if !ok {
+		return privilegeDenied(c, "close_votes", required)
+	}
+
+	// Moderadores têm voto vinculante
+	if isModerator(role) {
+		closedBy := uint64(userID)
+		if err := closeQuestion(tx, id, &closedBy, data.Reason, duplicateOf); err != nil {
+			fmt.Printf("Erro no banco: %v\n", err)
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao fechar pergunta"})
+		}
+		if err := tx.Commit(); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao fechar pergunta"})
+		}
+		return c.JSON(fiber.Map{"message": "Pergunta fechada com sucesso", "closed": true})
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO close_votes (question_id, user_id, vote_type, reason, duplicate_of, created_at)
+		VALUES ($1, $2, 'close', $3, $4, $5)
+		ON CONFLICT (question_id, user_id, vote_type)
+		DO UPDATE SET reason = EXCLUDED.reason, duplicate_of = EXCLUDED.duplicate_of, created_at = EXCLUDED.created_at
+	`, id, userID, data.Reason, duplicateOf, time.Now())
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao registrar voto de fechamento"})
+	}
+
+	var count int
+	if err := tx.Get(&count, "SELECT COUNT(*) FROM close_votes WHERE question_id = $1 AND vote_type = 'close'", id); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao registrar voto de fechamento"})
+	}
+
+	closed := false
+	if count >= closeVoteThreshold {
+		// O motivo mais votado vence; em caso de duplicata, a pergunta original mais indicada
+		var reason string
+		err := tx.Get(&reason, `
+			SELECT reason FROM close_votes
+			WHERE question_id = $1 AND vote_type = 'close'
+			GROUP BY reason
+			ORDER BY COUNT(*) DESC, MIN(created_at) ASC
+			LIMIT 1
+		`, id)
+		if err != nil {
+			fmt.Printf("Erro no banco: %v\n", err)
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao registrar voto de fechamento"})
+		}
+
+		var target *uint64
+		if reason == "duplicate" {
+			var dup uint64
+			err := tx.Get(&dup, `
+				SELECT duplicate_of FROM close_votes
+				WHERE question_id = $1 AND vote_type = 'close' AND duplicate_of IS NOT NULL
+				GROUP BY duplicate_of
+				ORDER BY COUNT(*) DESC, MIN(created_at) ASC
+				LIMIT 1
+			`, id)
+			if err == nil {
+				target = &dup
+			}
+		}
+
+		if err := closeQuestion(tx, id, nil, reason, target); err != nil {
+			fmt.Printf("Erro no banco: %v\n", err)
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao fechar pergunta"})
+		}
+		closed = true
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao registrar voto de fechamento"})
+	}
+
+	if closed {
+		return c.JSON(fiber.Map{"message": "Pergunta fechada pela comunidade", "closed": true, "votes": count})
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"message":   "Voto de fechamento registrado",
+		"closed":    false,
+		"votes":     count,
+		"threshold": closeVoteThreshold,
+	})
+}
+
+// Reabrir pergunta (moderadores reabrem direto, demais usuários registram voto)
+func ReopenQuestion(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	userID := c.Locals("user_id").(int)
+	role := c.Locals("role").(string)
+
+	var question models.Question
+	err = database.DB.Get(&question, "SELECT id, closed_at FROM questions WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Pergunta não encontrada"})
+	}
+
+	if question.ClosedAt == nil {
+		return c.Status(409).JSON(fiber.Map{"error": "Pergunta não está fechada"})
+	}
+
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao reabrir pergunta"})
+	}
+	defer tx.Rollback()
+
+	ok, required, err := hasPrivilege(c, "close_votes")
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao verificar privilégios"})
+	}
+	if !ok {
+		return privilegeDenied(c, "close_votes", required)
+	}
+
+	if isModerator(role) {
+		if err := reopenQuestion(tx, id); err != nil {
+			fmt.Printf("Erro no banco: %v\n", err)
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao reabrir pergunta"})
+		}
+		if err := tx.Commit(); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao reabrir pergunta"})
+		}
+		return c.JSON(fiber.Map{"message": "Pergunta reaberta com sucesso", "reopened": true})
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO close_votes (question_id, user_id, vote_type, created_at)
+		VALUES ($1, $2, 'reopen', $3)
+		ON CONFLICT (question_id, user_id, vote_type) DO NOTHING
+	`, id, userID, time.Now())
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao registrar voto de reabertura"})
+	}
+
+	var count int
+	if err := tx.Get(&count, "SELECT COUNT(*) FROM close_votes WHERE question_id = $1 AND vote_type = 'reopen'", id); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao registrar voto de reabertura"})
+	}
+
+	reopened := false
+	if count >= closeVoteThreshold {
+		if err := reopenQuestion(tx, id); err != nil {
+			fmt.Printf("Erro no banco: %v\n", err)
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao reabrir pergunta"})
+		}
+		reopened = true
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao registrar voto de reabertura"})
+	}
+
+	if reopened {
+		return c.JSON(fiber.Map{"message": "Pergunta reaberta pela comunidade", "reopened": true, "votes": count})
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"message":   "Voto de reabertura registrado",
+		"reopened":  false,
+		"votes":     count,
+		"threshold": closeVoteThreshold,
+	})
+}
+
+// Listar votos de fechamento/reabertura pendentes de uma pergunta
+func GetCloseVotes(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	votes := []models.CloseVote{}
+	err = database.DB.Select(&votes, `
+		SELECT * FROM close_votes
+		WHERE question_id = $1
+		ORDER BY created_at ASC
+	`, id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar votos de fechamento"})
+	}
+
+	return c.JSON(votes)
+}
+
+// closeQuestion marca a pergunta como fechada e limpa os votos de fechamento pendentes.
+// closedBy nulo indica fechamento pela comunidade.
+func closeQuestion(tx *sqlx.Tx, id uint64, closedBy *uint64, reason string, duplicateOf *uint64) error {
+	_, err := tx.Exec(`
+		UPDATE questions
+		SET closed_at = $1, closed_by = $2, close_reason = $3, duplicate_of = $4
+		WHERE id = $5
+	`, time.Now(), closedBy, reason, duplicateOf, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM close_votes WHERE question_id = $1", id)
+	return err
+}
+
+// reopenQuestion limpa o estado de fechamento e os votos pendentes.
+func reopenQuestion(tx *sqlx.Tx, id uint64) error {
+	_, err := tx.Exec(`
+		UPDATE questions
+		SET closed_at = NULL, closed_by = NULL, close_reason = NULL, duplicate_of = NULL
+		WHERE id = $1
+	`, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM close_votes WHERE question_id = $1", id)
+	return err
+}
+
+// includeClosed lê o filtro de listagem; perguntas fechadas ficam de fora por padrão
+func includeClosed(c *fiber.Ctx) bool {
+	return c.QueryBool("include_closed", false)
+}
@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"msu-forum/database"
+	"msu-forum/models"
+	"msu-forum/realtime"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/lib/pq"
+)
+
+// Comentários exibidos por post antes de recolher o restante
+const commentPreviewCount = 5
+
+type commentView struct {
+	models.Comment
+	Username string       `json:"username" db:"username"`
+	Mentions []mentionRef `json:"mentions" db:"-"`
+}
+
+// commentThread é anexado às perguntas e respostas: os primeiros comentários
+// e o total, para o frontend exibir "mostrar mais N"
+type commentThread struct {
+	Comments     []commentView `json:"comments" db:"-"`
+	CommentCount int           `json:"comment_count" db:"-"`
+}
+
+// Criar comentário em uma pergunta ou resposta
+func CreateComment(c *fiber.Ctx) error {
+	var data struct {
+		PostType string `json:"post_type" validate:"required,oneof=question answer"`
+		PostID   uint64 `json:"post_id" validate:"required"`
+		Body     string `json:"body" validate:"required,min=15,max=600"`
+	}
+
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "JSON inválido"})
+	}
+
+	if err := Validate.Struct(data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	locked, err := isPostLocked(data.PostType, data.PostID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Post não encontrado"})
+	}
+
+	if locked {
+		return c.Status(403).JSON(fiber.Map{"error": "Pergunta trancada não aceita comentários"})
+	}
+
+	userID := c.Locals("user_id").(int)
+	now := time.Now()
+
+	// Comentar nos próprios posts é sempre permitido
+	if ownerID, _ := postOwner(data.PostType, data.PostID); ownerID != uint64(userID) {
+		ok, required, err := hasPrivilege(c, "comment")
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao verificar privilégios"})
+		}
+		if !ok {
+			return privilegeDenied(c, "comment", required)
+		}
+	}
+
+	var commentID uint64
+	err = database.DB.QueryRow(`
+		INSERT INTO comments (post_type, post_id, user_id, body, votes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, 0, $5, $5) RETURNING id
+	`, data.PostType, data.PostID, userID, data.Body, now).Scan(&commentID)
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar comentário"})
+	}
+
+	mentions := recordMentions("comment", commentID, userID, data.Body)
+	queueBadgeCheck(uint64(userID))
+
+	if questionID, err := questionIDForPost(data.PostType, data.PostID); err == nil {
+		realtime.Publish(realtime.QuestionTopic(questionID), "comment.created", fiber.Map{
+			"id": commentID, "post_type": data.PostType, "post_id": data.PostID, "user_id": userID, "body": data.Body,
+		})
+	}
+
+	return c.Status(201).JSON(fiber.Map{"id": commentID, "message": "Comentário criado com sucesso", "mentions": mentions})
+}
+
+// Listar todos os comentários de um post (versão expandida)
+func GetComments(c *fiber.Ctx) error {
+	postType := c.Query("post_type")
+	if postType != "question" && postType != "answer" {
+		return c.Status(400).JSON(fiber.Map{"error": "post_type deve ser 'question' ou 'answer'"})
+	}
+
+	postID, err := strconv.ParseUint(c.Query("post_id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "post_id inválido"})
+	}
+
+	// Comentários de posts excluídos ficam ocultos junto com o post
+	comments := []commentView{}
+	err = database.DB.Select(&comments, `
+		SELECT c.*, u.username
+		FROM comments c
+		LEFT JOIN users u ON c.user_id = u.id
+		LEFT JOIN answers a ON c.post_type = 'answer' AND a.id = c.post_id
+		JOIN questions q ON q.id = CASE WHEN c.post_type = 'answer' THEN a.question_id ELSE c.post_id END
+		WHERE c.post_type = $1 AND c.post_id = $2 AND c.deleted_at IS NULL
+		  AND q.deleted_at IS NULL AND (c.post_type = 'question' OR a.deleted_at IS NULL)
+		ORDER BY c.created_at ASC
+	`, postType, postID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar comentários"})
+	}
+
+	if err := attachCommentMentions(comments); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar menções"})
+	}
+
+	return c.JSON(comments)
+}
+
+// Atualizar comentário (apenas o autor)
+func UpdateComment(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	var data struct {
+		Body string `json:"body" validate:"required,min=15,max=600"`
+	}
+
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "JSON inválido"})
+	}
+
+	if err := Validate.Struct(data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos"})
+	}
+
+	userID := c.Locals("user_id").(int)
+
+	var comment models.Comment
+	err = database.DB.Get(&comment, "SELECT * FROM comments WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Comentário não encontrado"})
+	}
+
+	if uint64(userID) != comment.UserID {
+		return c.Status(403).JSON(fiber.Map{"error": "Sem permissão para editar este comentário"})
+	}
+
+	if locked, _ := isPostLocked(comment.PostType, comment.PostID); locked {
+		return c.Status(403).JSON(fiber.Map{"error": "Pergunta trancada não permite edições"})
+	}
+
+	_, err = database.DB.Exec("UPDATE comments SET body = $1, updated_at = $2 WHERE id = $3", data.Body, time.Now(), id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar comentário"})
+	}
+
+	mentions := recordMentions("comment", id, userID, data.Body)
+
+	return c.JSON(fiber.Map{"message": "Comentário atualizado com sucesso", "mentions": mentions})
+}
+
+// Deletar comentário (autor ou moderadores)
+func DeleteComment(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	userID := c.Locals("user_id").(int)
+	role := c.Locals("role").(string)
+
+	var comment models.Comment
+	err = database.DB.Get(&comment, "SELECT * FROM comments WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Comentário não encontrado"})
+	}
+
+	if uint64(userID) != comment.UserID && !isModerator(role) {
+		return c.Status(403).JSON(fiber.Map{"error": "Sem permissão para deletar este comentário"})
+	}
+
+	_, err = database.DB.Exec("UPDATE comments SET deleted_at = $1 WHERE id = $2", time.Now(), id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar comentário"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Comentário deletado com sucesso"})
+}
+
+// Votar positivamente em um comentário (votar de novo remove o voto)
+func UpvoteComment(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	userID := c.Locals("user_id").(int)
+
+	var comment models.Comment
+	err = database.DB.Get(&comment, "SELECT * FROM comments WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Comentário não encontrado"})
+	}
+
+	if uint64(userID) == comment.UserID {
+		return c.Status(400).JSON(fiber.Map{"error": "Não é possível votar no próprio comentário"})
+	}
+
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao votar no comentário"})
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO comment_votes (comment_id, user_id, created_at) VALUES ($1, $2, $3)
+		ON CONFLICT (comment_id, user_id) DO NOTHING
+	`, id, userID, time.Now())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao votar no comentário"})
+	}
+
+	delta := 1
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		// Voto já existia: remover
+		if _, err := tx.Exec("DELETE FROM comment_votes WHERE comment_id = $1 AND user_id = $2", id, userID); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao votar no comentário"})
+		}
+		delta = -1
+	}
+
+	var votes int32
+	if err := tx.Get(&votes, "UPDATE comments SET votes = votes + $1 WHERE id = $2 RETURNING votes", delta, id); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao votar no comentário"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao votar no comentário"})
+	}
+
+	return c.JSON(fiber.Map{"votes": votes, "voted": delta > 0})
+}
+
+// loadCommentThreads busca os primeiros comentários (em ordem cronológica) e o
+// total de comentários de cada post informado
+func loadCommentThreads(postType string, postIDs []uint64) (map[uint64]commentThread, error) {
+	threads := make(map[uint64]commentThread, len(postIDs))
+	if len(postIDs) == 0 {
+		return threads, nil
+	}
+
+	var rows []struct {
+		commentView
+		Position int `db:"position"`
+		Total    int `db:"total"`
+	}
+
+	err := database.DB.Select(&rows, `
+		SELECT * FROM (
+			SELECT c.*, u.username,
+			       ROW_NUMBER() OVER (PARTITION BY c.post_id ORDER BY c.created_at ASC) AS position,
+			       COUNT(*) OVER (PARTITION BY c.post_id) AS total
+			FROM comments c
+			LEFT JOIN users u ON c.user_id = u.id
+			WHERE c.post_type = $1 AND c.post_id = ANY($2) AND c.deleted_at IS NULL
+		) ranked
+		WHERE position <= $3
+		ORDER BY post_id, position
+	`, postType, pq.Array(postIDs), commentPreviewCount)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	commentIDs := make([]uint64, len(rows))
+	for i, row := range rows {
+		commentIDs[i] = row.ID
+	}
+
+	mentions, err := loadMentions("comment", commentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		row.Mentions = mentions[row.ID]
+		thread := threads[row.PostID]
+		thread.Comments = append(thread.Comments, row.commentView)
+		thread.CommentCount = row.Total
+		threads[row.PostID] = thread
+	}
+
+	for _, id := range postIDs {
+		if thread := threads[id]; thread.Comments == nil {
+			thread.Comments = []commentView{}
+			threads[id] = thread
+		}
+	}
+
+	return threads, nil
+}
+
+// attachCommentMentions preenche as menções de cada comentário
+func attachCommentMentions(comments []commentView) error {
+	ids := make([]uint64, len(comments))
+	for i, comment := range comments {
+		ids[i] = comment.ID
+	}
+
+	mentions, err := loadMentions("comment", ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range comments {
+		comments[i].Mentions = mentions[comments[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"fmt"
+	"msu-forum/database"
+	"msu-forum/models"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// Prazo para o autor desfazer a exclusão do próprio post
+	undeleteWindow = 48 * time.Hour
+	// Posts excluídos há mais tempo que isso são removidos definitivamente
+	deletedRetention = 30 * 24 * time.Hour
+)
+
+// Restaurar pergunta excluída (autor dentro do prazo ou moderadores)
+func UndeleteQuestion(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	var question models.Question
+	err = database.DB.Get(&question, "SELECT user_id, deleted_at, deleted_by FROM questions WHERE id = $1 AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Pergunta excluída não encontrada"})
+	}
+
+	if !canUndelete(c, question.UserID, *question.DeletedAt, question.DeletedBy) {
+		return c.Status(403).JSON(fiber.Map{"error": "Sem permissão para restaurar esta pergunta"})
+	}
+
+	_, err = database.DB.Exec("UPDATE questions SET deleted_at = NULL, deleted_by = NULL WHERE id = $1", id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao restaurar pergunta"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Pergunta restaurada com sucesso"})
+}
+
+// Restaurar resposta excluída (autor dentro do prazo ou moderadores)
+func UndeleteAnswer(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	var answer models.Answer
+	err = database.DB.Get(&answer, "SELECT user_id, question_id, deleted_at, deleted_by FROM answers WHERE id = $1 AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Resposta excluída não encontrada"})
+	}
+
+	if !canUndelete(c, answer.UserID, *answer.DeletedAt, answer.DeletedBy) {
+		return c.Status(403).JSON(fiber.Map{"error": "Sem permissão para restaurar esta resposta"})
+	}
+
+	if !questionExists(answer.QuestionID) {
+		return c.Status(409).JSON(fiber.Map{"error": "Restaure a pergunta antes de restaurar a resposta"})
+	}
+
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao restaurar resposta"})
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE answers SET deleted_at = NULL, deleted_by = NULL WHERE id = $1", id); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao restaurar resposta"})
+	}
+
+	if _, err := tx.Exec("UPDATE questions SET answer_count = answer_count + 1 WHERE id = $1", answer.QuestionID); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao restaurar resposta"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao restaurar resposta"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Resposta restaurada com sucesso"})
+}
+
+// Listar posts excluídos (apenas moderadores)
+func GetDeletedPosts(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	offset := (page - 1) * limit
+
+	switch c.Query("type", "question") {
+	case "question":
+		var questions []struct {
+			models.Question
+			Username string `json:"username" db:"username"`
+		}
+		err := database.DB.Select(&questions, `
+			SELECT q.*, u.username
+			FROM questions q
+			LEFT JOIN users u ON q.user_id = u.id
+			WHERE q.deleted_at IS NOT NULL
+			ORDER BY q.deleted_at DESC
+			LIMIT $1 OFFSET $2
+		`, limit, offset)
+		if err != nil {
+			fmt.Printf("Erro no banco: %v\n", err)
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar perguntas excluídas"})
+		}
+		return c.JSON(questions)
+
+	case "answer":
+		var answers []struct {
+			models.Answer
+			Username      string `json:"username" db:"username"`
+			QuestionTitle string `json:"question_title" db:"question_title"`
+		}
+		err := database.DB.Select(&answers, `
+			SELECT a.*, u.username, q.title as question_title
+			FROM answers a
+			LEFT JOIN users u ON a.user_id = u.id
+			LEFT JOIN questions q ON a.question_id = q.id
+			WHERE a.deleted_at IS NOT NULL
+			ORDER BY a.deleted_at DESC
+			LIMIT $1 OFFSET $2
+		`, limit, offset)
+		if err != nil {
+			fmt.Printf("Erro no banco: %v\n", err)
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar respostas excluídas"})
+		}
+		return c.JSON(answers)
+	}
+
+	return c.Status(400).JSON(fiber.Map{"error": "type deve ser 'question' ou 'answer'"})
+}
+
+// PurgeDeletedPosts remove definitivamente os posts excluídos há mais tempo que a
+// retenção, junto com votos, comentários e menções (que não têm FK para os posts).
+// Executado pelo agendador.
+func PurgeDeletedPosts() error {
+	cutoff := time.Now().Add(-deletedRetention)
+
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Votos, favoritos, reações, vínculos de anexos, menções e comentários não têm chave
+	// estrangeira para o post; os votos dos comentários saem em cascata
+	for _, table := range []string{"votes", "bookmarks", "post_reactions", "post_attachments", "mentions", "comments"} {
+		// Respostas expiradas ou pertencentes a perguntas expiradas
+		_, err = tx.Exec(`
+			DELETE FROM `+table+` v
+			USING answers a
+			LEFT JOIN questions q ON q.id = a.question_id
+			WHERE v.post_type = 'answer' AND v.post_id = a.id
+			  AND (a.deleted_at < $1 OR q.deleted_at < $1)
+		`, cutoff)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			DELETE FROM `+table+` v
+			USING questions q
+			WHERE v.post_type = 'question' AND v.post_id = q.id AND q.deleted_at < $1
+		`, cutoff)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Menções feitas nos comentários removidos
+	_, err = tx.Exec(`
+		DELETE FROM mentions m
+		WHERE m.post_type = 'comment' AND NOT EXISTS (SELECT 1 FROM comments c WHERE c.id = m.post_id)
+	`)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM answers WHERE deleted_at < $1", cutoff); err != nil {
+		return err
+	}
+
+	// Cascade remove as respostas restantes das perguntas expiradas
+	if _, err := tx.Exec("DELETE FROM questions WHERE deleted_at < $1", cutoff); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// canUndelete: moderadores restauram qualquer post; o autor apenas o que ele mesmo
+// excluiu e dentro do prazo
+func canUndelete(c *fiber.Ctx, authorID uint64, deletedAt time.Time, deletedBy *uint64) bool {
+	if isModerator(c.Locals("role").(string)) {
+		return true
+	}
+
+	userID := uint64(c.Locals("user_id").(int))
+	if userID != authorID || deletedBy == nil || *deletedBy != authorID {
+		return false
+	}
+
+	return time.Since(deletedAt) <= undeleteWindow
+}
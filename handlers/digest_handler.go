@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"msu-forum/database"
+	"msu-forum/mail"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	digestTokenPurpose  = "digest-unsubscribe"
+	digestSectionLimit  = 10
+	digestDailyPeriod   = 24 * time.Hour
+	digestWeeklyPeriod  = 7 * 24 * time.Hour
+	digestSendTolerance = time.Hour // margem para o job horário não pular um envio
+	digestTokenTTL      = 60 * 24 * time.Hour
+)
+
+// Página do link de descadastro: o GET só confirma e o POST efetiva, para que
+// leitores de e-mail e pré-carregamento de links não descadastrem ninguém
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html lang="pt-BR">
+<head><meta charset="utf-8"><title>MSU Forum</title></head>
+<body style="font-family: Arial, sans-serif; color: #222; max-width: 600px; margin: 40px auto;">
+  <p>{{.Message}}</p>
+  {{if .Token}}
+  <form method="post">
+    <input type="hidden" name="token" value="{{.Token}}">
+    <button type="submit">Cancelar resumo por e-mail</button>
+  </form>
+  {{end}}
+</body>
+</html>
+`))
+
+type digestSettings struct {
+	UserID     uint64     `json:"-" db:"user_id"`
+	Frequency  string     `json:"frequency" db:"frequency"`
+	SendHour   int        `json:"send_hour" db:"send_hour"` // hora UTC
+	LastSentAt *time.Time `json:"last_sent_at" db:"last_sent_at"`
+}
+
+type digestAnswer struct {
+	QuestionID    uint64 `db:"question_id"`
+	QuestionTitle string `db:"question_title"`
+	Username      string `db:"username"`
+}
+
+type digestQuestion struct {
+	ID      uint64 `db:"id"`
+	Title   string `db:"title"`
+	TagName string `db:"tag_name"`
+	Votes   int32  `db:"votes"`
+}
+
+type digestData struct {
+	Username       string
+	Period         string
+	BaseURL        string
+	UnsubscribeURL string
+	Answers        []digestAnswer
+	TagQuestions   []digestQuestion
+	TopQuestions   []digestQuestion
+}
+
+// Configuração do resumo por e-mail do usuário
+func GetDigestSettings(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	settings := digestSettings{Frequency: "off", SendHour: 12}
+	database.DB.Get(&settings, "SELECT * FROM digest_settings WHERE user_id = $1", userID)
+
+	return c.JSON(settings)
+}
+
+// Atualizar frequência e horário do resumo
+func UpdateDigestSettings(c *fiber.Ctx) error {
+	var data struct {
+		Frequency string `json:"frequency" validate:"required,oneof=off daily weekly"`
+		SendHour  int    `json:"send_hour" validate:"min=0,max=23"`
+	}
+
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "JSON inválido"})
+	}
+
+	if err := Validate.Struct(data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	userID := c.Locals("user_id").(int)
+
+	var settings digestSettings
+	err := database.DB.Get(&settings, `
+		INSERT INTO digest_settings (user_id, frequency, send_hour) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET frequency = EXCLUDED.frequency, send_hour = EXCLUDED.send_hour
+		RETURNING *
+	`, userID, data.Frequency, data.SendHour)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar resumo"})
+	}
+
+	return c.JSON(settings)
+}
+
+// Confirmar o descadastro do resumo pelo link assinado do e-mail (público)
+func ConfirmUnsubscribeDigest(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if _, err := verifyUserToken(digestTokenPurpose, token); err != nil {
+		return renderUnsubscribePage(c, 400, "Link de descadastro inválido ou expirado.", "")
+	}
+
+	return renderUnsubscribePage(c, 200, "Deseja deixar de receber o resumo por e-mail do MSU Forum?", token)
+}
+
+// Descadastrar do resumo (público). Aceita o formulário da página de confirmação e o
+// descadastro em um clique dos clientes de e-mail (RFC 8058), com o token na URL.
+func UnsubscribeDigest(c *fiber.Ctx) error {
+	token := c.FormValue("token")
+	if token == "" {
+		token = c.Query("token")
+	}
+
+	userID, err := verifyUserToken(digestTokenPurpose, token)
+	if err != nil {
+		return renderUnsubscribePage(c, 400, "Link de descadastro inválido ou expirado.", "")
+	}
+
+	_, err = database.DB.Exec("UPDATE digest_settings SET frequency = 'off' WHERE user_id = $1", userID)
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return renderUnsubscribePage(c, 500, "Erro ao cancelar o resumo. Tente novamente mais tarde.", "")
+	}
+
+	return renderUnsubscribePage(c, 200, "Pronto! Você não receberá mais o resumo por e-mail.", "")
+}
+
+func renderUnsubscribePage(c *fiber.Ctx, status int, message, token string) error {
+	var buf bytes.Buffer
+	if err := unsubscribePage.Execute(&buf, fiber.Map{"Message": message, "Token": token}); err != nil {
+		return c.Status(500).SendString(message)
+	}
+
+	c.Type("html", "utf-8")
+	return c.Status(status).Send(buf.Bytes())
+}
+
+// SendDigests envia os resumos devidos na hora atual. Executado de hora em hora pelo agendador.
+func SendDigests() error {
+	now := time.Now().UTC()
+
+	var recipients []struct {
+		digestSettings
+		Username string `db:"username"`
+		Email    string `db:"email"`
+	}
+
+	err := database.DB.Select(&recipients, `
+		SELECT d.*, u.username, u.email
+		FROM digest_settings d
+		JOIN users u ON u.id = d.user_id
+		WHERE d.frequency <> 'off' AND d.send_hour = $1
+		  AND u.is_active = true AND u.email IS NOT NULL AND u.email <> ''
+	`, now.Hour())
+	if err != nil {
+		return err
+	}
+
+	for _, r := range recipients {
+		period := digestDailyPeriod
+		if r.Frequency == "weekly" {
+			period = digestWeeklyPeriod
+		}
+
+		if r.LastSentAt != nil && now.Sub(*r.LastSentAt) < period-digestSendTolerance {
+			continue
+		}
+
+		since := now.Add(-period)
+		if r.LastSentAt != nil && r.LastSentAt.After(since) {
+			since = *r.LastSentAt
+		}
+
+		if err := sendDigest(r.UserID, r.Username, r.Email, r.Frequency, since); err != nil {
+			fmt.Printf("Erro ao enviar resumo para o usuário %d: %v\n", r.UserID, err)
+			continue
+		}
+
+		database.DB.Exec("UPDATE digest_settings SET last_sent_at = $1 WHERE user_id = $2", now, r.UserID)
+	}
+
+	return nil
+}
+
+func sendDigest(userID uint64, username, email, frequency string, since time.Time) error {
+	data := digestData{
+		Username: username,
+		Period:   "diário",
+		BaseURL:  os.Getenv("APP_URL"),
+	}
+	if frequency == "weekly" {
+		data.Period = "semanal"
+	}
+	// O descadastro é servido pela API, não pelo frontend de APP_URL
+	data.UnsubscribeURL = fmt.Sprintf("%s/digest/unsubscribe?token=%s",
+		strings.TrimRight(os.Getenv("API_URL"), "/"),
+		url.QueryEscape(signUserToken(digestTokenPurpose, userID, digestTokenTTL)))
+
+	err := database.DB.Select(&data.Answers, `
+		SELECT q.id AS question_id, q.title AS question_title, u.username
+		FROM answers a
+		JOIN questions q ON q.id = a.question_id AND q.deleted_at IS NULL
+		JOIN users u ON u.id = a.user_id
+		WHERE q.user_id = $1 AND a.user_id <> $1 AND a.created_at > $2 AND a.deleted_at IS NULL
+		ORDER BY a.created_at DESC
+		LIMIT $3
+	`, userID, since, digestSectionLimit)
+	if err != nil {
+		return err
+	}
+
+	err = database.DB.Select(&data.TagQuestions, `
+		SELECT DISTINCT ON (q.id) q.id, q.title, t.name AS tag_name, q.votes
+		FROM follows f
+		JOIN question_tags qt ON f.target_type = 'tag' AND qt.tag_id = f.target_id
+		JOIN questions q ON q.id = qt.question_id
+		JOIN tags t ON t.id = qt.tag_id
+		WHERE f.user_id = $1 AND q.user_id <> $1 AND q.created_at > $2
+		  AND q.deleted_at IS NULL AND q.closed_at IS NULL
+		ORDER BY q.id DESC
+		LIMIT $3
+	`, userID, since, digestSectionLimit)
+	if err != nil {
+		return err
+	}
+
+	err = database.DB.Select(&data.TopQuestions, `
+		SELECT q.id, q.title, '' AS tag_name, q.votes
+		FROM questions q
+		WHERE q.created_at > $1 AND q.deleted_at IS NULL AND q.closed_at IS NULL AND q.votes > 0
+		ORDER BY q.votes DESC, q.view_count DESC
+		LIMIT $2
+	`, since, digestSectionLimit)
+	if err != nil {
+		return err
+	}
+
+	// Nada de novo: não enviar e-mail vazio
+	if len(data.Answers) == 0 && len(data.TagQuestions) == 0 && len(data.TopQuestions) == 0 {
+		return nil
+	}
+
+	text, html, err := mail.Render("digest", data)
+	if err != nil {
+		return err
+	}
+
+	return mail.Default.Send(mail.Message{
+		To:      email,
+		Subject: fmt.Sprintf("Seu resumo %s do MSU Forum", data.Period),
+		Text:    text,
+		HTML:    html,
+		Headers: map[string]string{
+			"List-Unsubscribe":      "<" + data.UnsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	})
+}
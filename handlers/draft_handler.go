@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"msu-forum/database"
+	"msu-forum/models"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/lib/pq"
+)
+
+// Rascunhos sem alteração por mais tempo que isso são removidos pelo agendador
+const draftTTL = 30 * 24 * time.Hour
+
+// Listar rascunhos do usuário
+func GetDrafts(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	drafts := []models.Draft{}
+	err := database.DB.Select(&drafts, "SELECT * FROM drafts WHERE user_id = $1 ORDER BY updated_at DESC", userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar rascunhos"})
+	}
+
+	return c.JSON(drafts)
+}
+
+// Buscar rascunho de um alvo (target_id 0 para nova pergunta)
+func GetDraft(c *fiber.Ctx) error {
+	targetType, targetID, err := parseDraftTarget(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	userID := c.Locals("user_id").(int)
+
+	var draft models.Draft
+	err = database.DB.Get(&draft,
+		"SELECT * FROM drafts WHERE user_id = $1 AND target_type = $2 AND target_id = $3",
+		userID, targetType, targetID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Rascunho não encontrado"})
+	}
+
+	return c.JSON(draft)
+}
+
+// Salvar rascunho (autosave). O cliente envia a versão em que se baseou;
+// se outro dispositivo salvou antes, retorna 409 com o rascunho atual,
+// a menos que force=true (última escrita vence).
+func SaveDraft(c *fiber.Ctx) error {
+	var data struct {
+		TargetType string   `json:"target_type" validate:"required,oneof=question answer edit_question edit_answer"`
+		TargetID   uint64   `json:"target_id"`
+		Title      string   `json:"title" validate:"max=200"`
+		Body       string   `json:"body"`
+		Tags       []string `json:"tags" validate:"max=5"`
+		Version    int32    `json:"version"`
+		Force      bool     `json:"force"`
+	}
+
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "JSON inválido"})
+	}
+
+	if err := Validate.Struct(data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	if data.TargetType == "question" {
+		data.TargetID = 0
+	} else if data.TargetID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "target_id é obrigatório para este tipo de rascunho"})
+	}
+
+	if data.Tags == nil {
+		data.Tags = []string{}
+	}
+
+	userID := c.Locals("user_id").(int)
+	now := time.Now()
+
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao salvar rascunho"})
+	}
+	defer tx.Rollback()
+
+	var current models.Draft
+	err = tx.Get(&current,
+		"SELECT * FROM drafts WHERE user_id = $1 AND target_type = $2 AND target_id = $3 FOR UPDATE",
+		userID, data.TargetType, data.TargetID)
+	if err != nil && err != sql.ErrNoRows {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao salvar rascunho"})
+	}
+
+	var draft models.Draft
+	if err == nil {
+		if !data.Force && data.Version != current.Version {
+			return c.Status(409).JSON(fiber.Map{"error": "Rascunho alterado em outro dispositivo", "draft": current})
+		}
+
+		err = tx.Get(&draft, `
+			UPDATE drafts SET title = $1, body = $2, tags = $3, version = version + 1, updated_at = $4
+			WHERE id = $5
+			RETURNING *
+		`, data.Title, data.Body, pq.StringArray(data.Tags), now, current.ID)
+	} else {
+		// Sem rascunho prévio; um insert concorrente resulta em conflito
+		err = tx.Get(&draft, `
+			INSERT INTO drafts (user_id, target_type, target_id, title, body, tags, version, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, 1, $7, $7)
+			ON CONFLICT (user_id, target_type, target_id) DO NOTHING
+			RETURNING *
+		`, userID, data.TargetType, data.TargetID, data.Title, data.Body, pq.StringArray(data.Tags), now)
+		if err == sql.ErrNoRows {
+			return c.Status(409).JSON(fiber.Map{"error": "Rascunho alterado em outro dispositivo"})
+		}
+	}
+
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao salvar rascunho"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao salvar rascunho"})
+	}
+
+	return c.JSON(draft)
+}
+
+// Descartar rascunho
+func DeleteDraft(c *fiber.Ctx) error {
+	targetType, targetID, err := parseDraftTarget(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	userID := c.Locals("user_id").(int)
+
+	result, err := database.DB.Exec(
+		"DELETE FROM drafts WHERE user_id = $1 AND target_type = $2 AND target_id = $3",
+		userID, targetType, targetID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao descartar rascunho"})
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Rascunho não encontrado"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Rascunho descartado com sucesso"})
+}
+
+// PurgeStaleDrafts remove rascunhos abandonados. Executado pelo agendador.
+func PurgeStaleDrafts() error {
+	_, err := database.DB.Exec("DELETE FROM drafts WHERE updated_at < $1", time.Now().Add(-draftTTL))
+	return err
+}
+
+// clearDraft remove o rascunho após a publicação; falhas não impedem a publicação
+func clearDraft(userID int, targetType string, targetID uint64) {
+	_, err := database.DB.Exec(
+		"DELETE FROM drafts WHERE user_id = $1 AND target_type = $2 AND target_id = $3",
+		userID, targetType, targetID)
+	if err != nil {
+		fmt.Printf("Erro ao remover rascunho publicado: %v\n", err)
+	}
+}
+
+func parseDraftTarget(c *fiber.Ctx) (string, uint64, error) {
+	targetType := c.Params("targetType")
+	switch targetType {
+	case "question", "answer", "edit_question", "edit_answer":
+	default:
+		return "", 0, fmt.Errorf("Tipo de rascunho inválido")
+	}
+
+	targetID, err := strconv.ParseUint(c.Params("targetId"), 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("ID inválido")
+	}
+
+	return targetType, targetID, nil
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"msu-forum/database"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const feedMaxLimit = 50
+
+// feedItem é uma pergunta ou resposta vinda de algo que o usuário segue
+type feedItem struct {
+	Type       string    `json:"type" db:"type"` // "question" ou "answer"
+	PostID     uint64    `json:"post_id" db:"post_id"`
+	QuestionID uint64    `json:"question_id" db:"question_id"`
+	Title      string    `json:"title" db:"title"`
+	UserID     uint64    `json:"user_id" db:"user_id"`
+	Username   *string   `json:"username" db:"username"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// Feed personalizado: perguntas novas nas tags e dos usuários seguidos, e
+// respostas nas perguntas e dos usuários seguidos. Paginação por cursor.
+func GetFeed(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	if limit < 1 || limit > feedMaxLimit {
+		limit = 20
+	}
+
+	// Sem cursor, começa do item mais recente
+	before := time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+	beforeType, beforeID := "question", uint64(1<<63-1)
+	if cursor := c.Query("cursor"); cursor != "" {
+		var err error
+		before, beforeType, beforeID, err = decodeFeedCursor(cursor)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Cursor inválido"})
+		}
+	}
+
+	items := []feedItem{}
+	err := database.DB.Select(&items, `
+		WITH followed AS (
+			SELECT target_type, target_id FROM follows WHERE user_id = $1
+		)
+		SELECT items.*, u.username
+		FROM (
+			SELECT 'question' AS type, q.id AS post_id, q.id AS question_id, q.title, q.user_id, q.created_at
+			FROM questions q
+			WHERE q.deleted_at IS NULL AND q.user_id <> $1
+			  AND (q.user_id IN (SELECT target_id FROM followed WHERE target_type = 'user')
+			       OR EXISTS (
+			           SELECT 1 FROM question_tags qt
+			           JOIN followed f ON f.target_type = 'tag' AND f.target_id = qt.tag_id
+			           WHERE qt.question_id = q.id))
+			UNION ALL
+			SELECT 'answer', a.id, a.question_id, q.title, a.user_id, a.created_at
+			FROM answers a
+			JOIN questions q ON q.id = a.question_id AND q.deleted_at IS NULL
+			WHERE a.deleted_at IS NULL AND a.user_id <> $1
+			  AND (a.user_id IN (SELECT target_id FROM followed WHERE target_type = 'user')
+			       OR a.question_id IN (SELECT target_id FROM followed WHERE target_type = 'question'))
+		) items
+		LEFT JOIN users u ON u.id = items.user_id
+		WHERE (items.created_at, items.type, items.post_id) < ($2, $3, $4)
+		ORDER BY items.created_at DESC, items.type DESC, items.post_id DESC
+		LIMIT $5
+	`, userID, before, beforeType, beforeID, limit)
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar feed"})
+	}
+
+	var nextCursor *string
+	if len(items) == limit {
+		last := items[len(items)-1]
+		cursor := encodeFeedCursor(last.CreatedAt, last.Type, last.PostID)
+		nextCursor = &cursor
+	}
+
+	return c.JSON(fiber.Map{"items": items, "next_cursor": nextCursor})
+}
+
+// O cursor é a chave de ordenação do último item: data, tipo e ID
+func encodeFeedCursor(createdAt time.Time, itemType string, postID uint64) string {
+	raw := fmt.Sprintf("%s|%s|%d", createdAt.Format(time.RFC3339Nano), itemType, postID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeFeedCursor(cursor string) (time.Time, string, uint64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", 0, err
+	}
+
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 || (parts[1] != "question" && parts[1] != "answer") {
+		return time.Time{}, "", 0, fmt.Errorf("cursor malformado")
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return time.Time{}, "", 0, err
+	}
+
+	postID, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return time.Time{}, "", 0, err
+	}
+
+	return createdAt, parts[1], postID, nil
+}
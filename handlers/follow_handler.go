@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"fmt"
+	"msu-forum/database"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Seguir uma tag
+func FollowTag(c *fiber.Ctx) error {
+	return followTarget(c, "tag")
+}
+
+// Deixar de seguir uma tag
+func UnfollowTag(c *fiber.Ctx) error {
+	return unfollowTarget(c, "tag")
+}
+
+// Seguir uma pergunta
+func FollowQuestion(c *fiber.Ctx) error {
+	return followTarget(c, "question")
+}
+
+// Deixar de seguir uma pergunta
+func UnfollowQuestion(c *fiber.Ctx) error {
+	return unfollowTarget(c, "question")
+}
+
+// Seguir um usuário
+func FollowUser(c *fiber.Ctx) error {
+	return followTarget(c, "user")
+}
+
+// Deixar de seguir um usuário
+func UnfollowUser(c *fiber.Ctx) error {
+	return unfollowTarget(c, "user")
+}
+
+// Listar o que o usuário segue (?type=question|tag|user para filtrar)
+func GetFollows(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+	targetType := c.Query("type")
+
+	if _, ok := followTargets[targetType]; targetType != "" && !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "Tipo inválido"})
+	}
+
+	var follows []struct {
+		TargetType string    `json:"target_type" db:"target_type"`
+		TargetID   uint64    `json:"target_id" db:"target_id"`
+		Name       *string   `json:"name" db:"name"`
+		CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	}
+	err := database.DB.Select(&follows, `
+		SELECT f.target_type, f.target_id, f.created_at,
+		       COALESCE(q.title, t.name, u.username) AS name
+		FROM follows f
+		LEFT JOIN questions q ON f.target_type = 'question' AND q.id = f.target_id
+		LEFT JOIN tags t ON f.target_type = 'tag' AND t.id = f.target_id
+		LEFT JOIN users u ON f.target_type = 'user' AND u.id = f.target_id
+		WHERE f.user_id = $1 AND ($2 = '' OR f.target_type = $2)
+		ORDER BY f.created_at DESC
+	`, userID, targetType)
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar itens seguidos"})
+	}
+
+	return c.JSON(follows)
+}
+
+// followTargets descreve como validar cada tipo de alvo e as mensagens de resposta
+var followTargets = map[string]struct {
+	existsQuery string
+	notFound    string
+	followed    string
+}{
+	"tag":      {"SELECT EXISTS(SELECT 1 FROM tags WHERE id = $1)", "Tag não encontrada", "Tag seguida com sucesso"},
+	"question": {"SELECT EXISTS(SELECT 1 FROM questions WHERE id = $1 AND deleted_at IS NULL)", "Pergunta não encontrada", "Pergunta seguida com sucesso"},
+	"user":     {"SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", "Usuário não encontrado", "Usuário seguido com sucesso"},
+}
+
+func followTarget(c *fiber.Ctx, targetType string) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	target := followTargets[targetType]
+	userID := c.Locals("user_id").(int)
+
+	if targetType == "user" && id == uint64(userID) {
+		return c.Status(400).JSON(fiber.Map{"error": "Não é possível seguir a si mesmo"})
+	}
+
+	var exists bool
+	database.DB.Get(&exists, target.existsQuery, id)
+	if !exists {
+		return c.Status(404).JSON(fiber.Map{"error": target.notFound})
+	}
+
+	if err := follow(uint64(userID), targetType, id); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao seguir"})
+	}
+
+	return c.JSON(fiber.Map{"message": target.followed})
+}
+
+func unfollowTarget(c *fiber.Ctx, targetType string) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	userID := c.Locals("user_id").(int)
+
+	_, err = database.DB.Exec("DELETE FROM follows WHERE user_id = $1 AND target_type = $2 AND target_id = $3", userID, targetType, id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deixar de seguir"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Deixou de seguir com sucesso"})
+}
+
+// follow registra o acompanhamento; também usado para seguir automaticamente
+// as perguntas que o usuário cria ou responde
+func follow(userID uint64, targetType string, targetID uint64) error {
+	_, err := database.DB.Exec(`
+		INSERT INTO follows (user_id, target_type, target_id, created_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING
+	`, userID, targetType, targetID, time.Now())
+	return err
+}
+
+// notifyQuestionFollowers avisa quem segue a pergunta sobre uma nova resposta.
+// O autor da pergunta já recebe a notificação "answer" e fica de fora.
+func notifyQuestionFollowers(questionID, ownerID, actorID uint64) {
+	var followers []uint64
+	err := database.DB.Select(&followers, `
+		SELECT user_id FROM follows
+		WHERE target_type = 'question' AND target_id = $1 AND user_id NOT IN ($2, $3)
+	`, questionID, ownerID, actorID)
+	if err != nil {
+		fmt.Printf("Erro ao buscar seguidores da pergunta %d: %v\n", questionID, err)
+		return
+	}
+
+	for _, followerID := range followers {
+		notify(notificationEvent{
+			UserID:     followerID,
+			ActorID:    actorID,
+			Type:       "followed_answer",
+			PostType:   "question",
+			PostID:     questionID,
+			QuestionID: questionID,
+		})
+	}
+}
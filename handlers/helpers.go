@@ -0,0 +1,6 @@
+package handlers
+
+// isModerator indica se o papel tem poderes de moderação
+func isModerator(role string) bool {
+	return role == "Admin" || role == "Moderator"
+}
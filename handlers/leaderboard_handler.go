@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"fmt"
+	"msu-forum/database"
+	"msu-forum/models"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Posições guardadas em cada ranking (geral e por tag)
+const leaderboardSize = 100
+
+// Períodos dos rankings; season é o trimestre do calendário
+var leaderboardPeriods = []string{"week", "month", "season", "all"}
+
+// Pontos de cada métrica com o post de origem (para o ranking por tag) e o momento
+// em que foram ganhos
+var leaderboardMetrics = map[string]string{
+	"reputation": `
+		SELECT user_id, post_type, post_id, delta AS points, created_at AS at
+		FROM reputation_events
+	`,
+	// Reversões de aceite também são lançadas para quem aceitou (o próprio ator)
+	"accepted": `
+		SELECT user_id, post_type, post_id, CASE WHEN reason = 'accepted' THEN 1 ELSE -1 END AS points, created_at AS at
+		FROM reputation_events
+		WHERE reason = 'accepted' OR (reason = 'accept_reversal' AND actor_id IS DISTINCT FROM user_id)
+	`,
+	"answer_score": `
+		SELECT a.user_id, v.post_type, v.post_id, v.type AS points, v.created_at AS at
+		FROM votes v
+		JOIN answers a ON v.post_type = 'answer' AND a.id = v.post_id
+		WHERE a.deleted_at IS NULL AND v.user_id <> a.user_id
+	`,
+}
+
+// Ranking de usuários (?period=week|month|season|all, ?metric=reputation|accepted|answer_score,
+// ?tag_id= e ?date=AAAA-MM-DD para consultar um período anterior)
+func GetLeaderboard(c *fiber.Ctx) error {
+	period := c.Query("period", "week")
+	metric := c.Query("metric", "reputation")
+
+	validPeriod := false
+	for _, p := range leaderboardPeriods {
+		validPeriod = validPeriod || p == period
+	}
+	if _, ok := leaderboardMetrics[metric]; !ok || !validPeriod {
+		return c.Status(400).JSON(fiber.Map{"error": "period ou metric inválido"})
+	}
+
+	tagID, err := strconv.ParseUint(c.Query("tag_id", "0"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "tag_id inválido"})
+	}
+
+	date := time.Now()
+	if c.Query("date") != "" {
+		date, err = time.ParseInLocation("2006-01-02", c.Query("date"), time.Local)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "date deve estar no formato AAAA-MM-DD"})
+		}
+	}
+
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	offset := (page - 1) * limit
+
+	start, _ := leaderboardBounds(period, date)
+
+	var snapshot models.LeaderboardSnapshot
+	err = database.DB.Get(&snapshot, `
+		SELECT * FROM leaderboard_snapshots WHERE period = $1 AND metric = $2 AND period_start = $3
+	`, period, metric, start)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Ranking ainda não calculado para este período"})
+	}
+
+	entries := []struct {
+		models.LeaderboardEntry
+		Username  string  `json:"username" db:"username"`
+		AvatarURL *string `json:"avatar_url" db:"avatar_url"`
+	}{}
+	err = database.DB.Select(&entries, `
+		SELECT e.*, u.username, u.avatar_url
+		FROM leaderboard_entries e
+		JOIN users u ON u.id = e.user_id
+		WHERE e.snapshot_id = $1 AND COALESCE(e.tag_id, 0) = $2
+		ORDER BY e.rank, e.user_id
+		LIMIT $3 OFFSET $4
+	`, snapshot.ID, tagID, limit, offset)
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar ranking"})
+	}
+
+	return c.JSON(fiber.Map{"snapshot": snapshot, "tag_id": tagID, "entries": entries})
+}
+
+// RefreshLeaderboards recalcula os rankings do período atual e fecha o período
+// anterior se a última apuração foi feita antes do seu fim. Executado pelo agendador.
+func RefreshLeaderboards() error {
+	now := time.Now()
+
+	for _, period := range leaderboardPeriods {
+		start, end := leaderboardBounds(period, now)
+		prevStart, prevEnd := leaderboardBounds(period, start.Add(-time.Nanosecond))
+
+		for metric := range leaderboardMetrics {
+			if err := refreshLeaderboard(period, metric, start, end, now); err != nil {
+				return err
+			}
+
+			if period == "all" {
+				continue
+			}
+
+			var computedAt time.Time
+			err := database.DB.Get(&computedAt, `
+				SELECT computed_at FROM leaderboard_snapshots WHERE period = $1 AND metric = $2 AND period_start = $3
+			`, period, metric, prevStart)
+			if err == nil && !computedAt.Before(prevEnd) {
+				continue
+			}
+			if err := refreshLeaderboard(period, metric, prevStart, prevEnd, now); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// leaderboardBounds retorna o início e o fim do período que contém t. O ranking
+// geral começa na época Unix e vai até t.
+func leaderboardBounds(period string, t time.Time) (time.Time, time.Time) {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+
+	switch period {
+	case "week":
+		// Semanas começam na segunda-feira
+		start := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		return start, start.AddDate(0, 0, 7)
+	case "month":
+		start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+		return start, start.AddDate(0, 1, 0)
+	case "season":
+		month := time.Month((int(t.Month())-1)/3*3 + 1)
+		start := time.Date(t.Year(), month, 1, 0, 0, 0, 0, t.Location())
+		return start, start.AddDate(0, 3, 0)
+	}
+
+	return time.Unix(0, 0).In(t.Location()), t
+}
+
+// refreshLeaderboard substitui as posições de um ranking (geral e por tag) numa transação
+func refreshLeaderboard(period, metric string, start, end, now time.Time) error {
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var snapshotID uint64
+	err = tx.Get(&snapshotID, `
+		INSERT INTO leaderboard_snapshots (period, metric, period_start, period_end, computed_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (period, metric, period_start)
+		DO UPDATE SET period_end = EXCLUDED.period_end, computed_at = EXCLUDED.computed_at
+		RETURNING id
+	`, period, metric, start, end, now)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM leaderboard_entries WHERE snapshot_id = $1", snapshotID); err != nil {
+		return err
+	}
+
+	// Usuários banidos ficam fora dos rankings
+	_, err = tx.Exec(`
+		WITH scored AS (`+leaderboardMetrics[metric]+`),
+		windowed AS (
+			SELECT * FROM scored WHERE at >= $2 AND at < $3
+		),
+		totals AS (
+			SELECT user_id, NULL::int AS tag_id, SUM(points) AS score
+			FROM windowed
+			GROUP BY user_id
+			UNION ALL
+			SELECT w.user_id, qt.tag_id, SUM(w.points) AS score
+			FROM windowed w
+			LEFT JOIN answers a ON w.post_type = 'answer' AND a.id = w.post_id
+			JOIN question_tags qt ON qt.question_id = CASE WHEN w.post_type = 'answer' THEN a.question_id ELSE w.post_id END
+			GROUP BY w.user_id, qt.tag_id
+		),
+		ranked AS (
+			SELECT t.*,
+			       RANK() OVER (PARTITION BY t.tag_id ORDER BY t.score DESC) AS rank,
+			       ROW_NUMBER() OVER (PARTITION BY t.tag_id ORDER BY t.score DESC, t.user_id) AS position
+			FROM totals t
+			JOIN users u ON u.id = t.user_id AND u.is_active = true
+			WHERE t.score > 0
+		)
+		INSERT INTO leaderboard_entries (snapshot_id, tag_id, user_id, rank, score)
+		SELECT $1, tag_id, user_id, rank, score
+		FROM ranked
+		WHERE position <= $4
+	`, snapshotID, start, end, leaderboardSize)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
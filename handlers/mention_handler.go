@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"fmt"
+	"msu-forum/database"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/lib/pq"
+)
+
+// @Nickname precedido de início de texto ou caractere que não forma palavra
+// (evita capturar e-mails como nome@dominio)
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@])@([\p{L}\p{N}_]{2,50})`)
+
+// Limite de menções processadas por post
+const maxMentionsPerPost = 20
+
+// mentionRef é devolvida junto com os posts para o frontend criar os links
+type mentionRef struct {
+	PostID   uint64 `json:"-" db:"post_id"`
+	UserID   uint64 `json:"user_id" db:"mentioned_user_id"`
+	Username string `json:"username" db:"username"`
+}
+
+// Listar menções ao usuário atual
+func GetMyMentions(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	offset := (page - 1) * limit
+
+	// question_id permite ao frontend abrir a pergunta em qualquer tipo de menção
+	query := `
+		SELECT m.id, m.post_type, m.post_id, m.author_id, m.created_at,
+		       u.username AS author_username,
+		       COALESCE(q.id, aq.id, cq.id, caq.id) AS question_id,
+		       COALESCE(q.title, aq.title, cq.title, caq.title) AS question_title
+		FROM mentions m
+		LEFT JOIN users u ON u.id = m.author_id
+		LEFT JOIN questions q ON m.post_type = 'question' AND q.id = m.post_id AND q.deleted_at IS NULL
+		LEFT JOIN answers a ON m.post_type = 'answer' AND a.id = m.post_id AND a.deleted_at IS NULL
+		LEFT JOIN questions aq ON aq.id = a.question_id AND aq.deleted_at IS NULL
+		LEFT JOIN comments cm ON m.post_type = 'comment' AND cm.id = m.post_id AND cm.deleted_at IS NULL
+		LEFT JOIN questions cq ON cm.post_type = 'question' AND cq.id = cm.post_id AND cq.deleted_at IS NULL
+		LEFT JOIN answers ca ON cm.post_type = 'answer' AND ca.id = cm.post_id AND ca.deleted_at IS NULL
+		LEFT JOIN questions caq ON caq.id = ca.question_id AND caq.deleted_at IS NULL
+		WHERE m.mentioned_user_id = $1
+		  AND COALESCE(q.id, aq.id, cq.id, caq.id) IS NOT NULL
+		ORDER BY m.created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	mentions := []struct {
+		ID             uint64    `json:"id" db:"id"`
+		PostType       string    `json:"post_type" db:"post_type"`
+		PostID         uint64    `json:"post_id" db:"post_id"`
+		AuthorID       uint64    `json:"author_id" db:"author_id"`
+		AuthorUsername string    `json:"author_username" db:"author_username"`
+		QuestionID     uint64    `json:"question_id" db:"question_id"`
+		QuestionTitle  string    `json:"question_title" db:"question_title"`
+		CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	}{}
+
+	err := database.DB.Select(&mentions, query, userID, limit, offset)
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar menções"})
+	}
+
+	return c.JSON(mentions)
+}
+
+// extractMentions devolve os nomes mencionados no texto, sem repetição
+func extractMentions(body string) []string {
+	seen := map[string]bool{}
+	names := []string{}
+
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		key := strings.ToLower(match[1])
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		names = append(names, match[1])
+
+		if len(names) == maxMentionsPerPost {
+			break
+		}
+	}
+
+	return names
+}
+
+// saveMentions resolve as menções do texto contra users.username e sincroniza as
+// menções gravadas para o post, mantendo as que continuam no texto. Autocitações
+// são ignoradas. Retorna todas as menções e as que foram adicionadas agora.
+func saveMentions(postType string, postID uint64, authorID int, body string) ([]mentionRef, []mentionRef, error) {
+	refs := []mentionRef{}
+	added := []mentionRef{}
+
+	names := extractMentions(body)
+	lowered := make([]string, len(names))
+	for i, name := range names {
+		lowered[i] = strings.ToLower(name)
+	}
+
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return refs, added, err
+	}
+	defer tx.Rollback()
+
+	if len(names) > 0 {
+		err = tx.Select(&refs, `
+			SELECT $1::integer AS post_id, id AS mentioned_user_id, username
+			FROM users
+			WHERE LOWER(username) = ANY($2) AND id <> $3 AND is_active = true
+		`, postID, pq.Array(lowered), authorID)
+		if err != nil {
+			return refs, added, err
+		}
+	}
+
+	userIDs := make([]uint64, len(refs))
+	for i, ref := range refs {
+		userIDs[i] = ref.UserID
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM mentions WHERE post_type = $1 AND post_id = $2 AND mentioned_user_id <> ALL($3)
+	`, postType, postID, pq.Array(userIDs))
+	if err != nil {
+		return refs, added, err
+	}
+
+	now := time.Now()
+	for _, ref := range refs {
+		result, err := tx.Exec(`
+			INSERT INTO mentions (post_type, post_id, mentioned_user_id, author_id, created_at)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (post_type, post_id, mentioned_user_id) DO NOTHING
+		`, postType, postID, ref.UserID, authorID, now)
+		if err != nil {
+			return refs, added, err
+		}
+		if rows, _ := result.RowsAffected(); rows > 0 {
+			added = append(added, ref)
+		}
+	}
+
+	return refs, added, tx.Commit()
+}
+
+// recordMentions grava as menções sem impedir a publicação em caso de falha e
+// notifica apenas quem passou a ser mencionado. authorID é o autor do post, mesmo
+// quando outra pessoa o edita.
+func recordMentions(postType string, postID uint64, authorID int, body string) []mentionRef {
+	refs, added, err := saveMentions(postType, postID, authorID, body)
+	if err != nil {
+		fmt.Printf("Erro ao gravar menções do post %s %d: %v\n", postType, postID, err)
+		return []mentionRef{}
+	}
+
+	for _, ref := range added {
+		notify(notificationEvent{
+			UserID:   ref.UserID,
+			ActorID:  uint64(authorID),
+			Type:     "mention",
+			PostType: postType,
+			PostID:   postID,
+		})
+	}
+	return refs
+}
+
+// loadMentions busca as menções resolvidas de cada post informado
+func loadMentions(postType string, postIDs []uint64) (map[uint64][]mentionRef, error) {
+	result := make(map[uint64][]mentionRef, len(postIDs))
+	for _, id := range postIDs {
+		result[id] = []mentionRef{}
+	}
+	if len(postIDs) == 0 {
+		return result, nil
+	}
+
+	var refs []mentionRef
+	err := database.DB.Select(&refs, `
+		SELECT m.post_id, m.mentioned_user_id, u.username
+		FROM mentions m
+		JOIN users u ON u.id = m.mentioned_user_id
+		WHERE m.post_type = $1 AND m.post_id = ANY($2)
+		ORDER BY m.id
+	`, postType, pq.Array(postIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ref := range refs {
+		result[ref.PostID] = append(result[ref.PostID], ref)
+	}
+	return result, nil
+}
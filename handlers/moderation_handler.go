@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"msu-forum/database"
+	"msu-forum/models"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Duração padrão do destaque quando não informada
+const defaultFeatureDuration = 7 * 24 * time.Hour
+
+// Fixar pergunta na listagem geral ou dentro de uma tag (apenas moderadores)
+func PinQuestion(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	var data struct {
+		TagID *uint64 `json:"tag_id"`
+	}
+
+	if err := c.BodyParser(&data); err != nil && len(c.Body()) > 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "JSON inválido"})
+	}
+
+	if !questionExists(id) {
+		return c.Status(404).JSON(fiber.Map{"error": "Pergunta não encontrada"})
+	}
+
+	if data.TagID != nil {
+		var tagged bool
+		database.DB.Get(&tagged, "SELECT EXISTS(SELECT 1 FROM question_tags WHERE question_id = $1 AND tag_id = $2)", id, *data.TagID)
+		if !tagged {
+			return c.Status(400).JSON(fiber.Map{"error": "A pergunta não possui esta tag"})
+		}
+	}
+
+	userID := c.Locals("user_id").(int)
+
+	var pin models.QuestionPin
+	err = database.DB.Get(&pin, `
+		INSERT INTO question_pins (question_id, tag_id, pinned_by, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (question_id, COALESCE(tag_id, 0)) DO NOTHING
+		RETURNING *
+	`, id, data.TagID, userID, time.Now())
+	if err == sql.ErrNoRows {
+		return c.Status(409).JSON(fiber.Map{"error": "Pergunta já está fixada"})
+	}
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao fixar pergunta"})
+	}
+
+	return c.Status(201).JSON(pin)
+}
+
+// Desafixar pergunta (tag_id na query para remover o destaque de uma tag)
+func UnpinQuestion(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	var result sql.Result
+	if tagParam := c.Query("tag_id"); tagParam != "" {
+		tagID, parseErr := strconv.ParseUint(tagParam, 10, 64)
+		if parseErr != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "ID da tag inválido"})
+		}
+		result, err = database.DB.Exec("DELETE FROM question_pins WHERE question_id = $1 AND tag_id = $2", id, tagID)
+	} else {
+		result, err = database.DB.Exec("DELETE FROM question_pins WHERE question_id = $1 AND tag_id IS NULL", id)
+	}
+
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao desafixar pergunta"})
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Pergunta não está fixada"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Pergunta desafixada com sucesso"})
+}
+
+// Destacar pergunta por um período (apenas moderadores)
+func FeatureQuestion(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	var data struct {
+		Until *time.Time `json:"until"`
+	}
+
+	if err := c.BodyParser(&data); err != nil && len(c.Body()) > 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "JSON inválido"})
+	}
+
+	until := time.Now().Add(defaultFeatureDuration)
+	if data.Until != nil {
+		if !data.Until.After(time.Now()) {
+			return c.Status(400).JSON(fiber.Map{"error": "until deve estar no futuro"})
+		}
+		until = *data.Until
+	}
+
+	result, err := database.DB.Exec("UPDATE questions SET featured_until = $1 WHERE id = $2", until, id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao destacar pergunta"})
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Pergunta não encontrada"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Pergunta destacada com sucesso", "featured_until": until})
+}
+
+// Remover destaque da pergunta
+func UnfeatureQuestion(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	result, err := database.DB.Exec("UPDATE questions SET featured_until = NULL WHERE id = $1", id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao remover destaque"})
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Pergunta não encontrada"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Destaque removido com sucesso"})
+}
+
+// Trancar pergunta: sem novas respostas, votos ou edições (apenas moderadores)
+func LockQuestion(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	userID := c.Locals("user_id").(int)
+
+	result, err := database.DB.Exec(
+		"UPDATE questions SET locked_at = $1, locked_by = $2 WHERE id = $3 AND locked_at IS NULL",
+		time.Now(), userID, id,
+	)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao trancar pergunta"})
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		if !questionExists(id) {
+			return c.Status(404).JSON(fiber.Map{"error": "Pergunta não encontrada"})
+		}
+		return c.Status(409).JSON(fiber.Map{"error": "Pergunta já está trancada"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Pergunta trancada com sucesso"})
+}
+
+// Destrancar pergunta
+func UnlockQuestion(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	result, err := database.DB.Exec("UPDATE questions SET locked_at = NULL, locked_by = NULL WHERE id = $1 AND locked_at IS NOT NULL", id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao destrancar pergunta"})
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		if !questionExists(id) {
+			return c.Status(404).JSON(fiber.Map{"error": "Pergunta não encontrada"})
+		}
+		return c.Status(409).JSON(fiber.Map{"error": "Pergunta não está trancada"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Pergunta destrancada com sucesso"})
+}
+
+// questionExists indica se a pergunta existe
+func questionExists(id uint64) bool {
+	var exists bool
+	database.DB.Get(&exists, "SELECT EXISTS(SELECT 1 FROM questions WHERE id = $1 AND deleted_at IS NULL)", id)
+	return exists
+}
+
+// isPostLocked indica se o post (ou a pergunta da resposta) está trancado.
+// Retorna sql.ErrNoRows se o post não existir ou tiver sido excluído.
+func isPostLocked(postType string, postID uint64) (bool, error) {
+	var lockedAt sql.NullTime
+	var err error
+	if postType == "question" {
+		err = database.DB.Get(&lockedAt, "SELECT locked_at FROM questions WHERE id = $1 AND deleted_at IS NULL", postID)
+	} else {
+		err = database.DB.Get(&lockedAt, `
+			SELECT q.locked_at FROM answers a
+			JOIN questions q ON q.id = a.question_id
+			WHERE a.id = $1 AND a.deleted_at IS NULL AND q.deleted_at IS NULL
+		`, postID)
+	}
+	if err != nil {
+		return false, err
+	}
+	return lockedAt.Valid, nil
+}
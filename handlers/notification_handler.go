@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"fmt"
+	"msu-forum/database"
+	"msu-forum/models"
+	"msu-forum/realtime"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Tipos de notificação suportados
+var notificationTypes = []string{"answer", "accepted", "upvote", "mention", "followed_answer"}
+
+// notificationEvent descreve algo que aconteceu com o conteúdo de um usuário
+type notificationEvent struct {
+	UserID     uint64 // quem recebe
+	ActorID    uint64 // quem causou
+	Type       string
+	PostType   string
+	PostID     uint64
+	QuestionID uint64 // resolvido a partir do post quando zero
+}
+
+type notificationView struct {
+	models.Notification
+	ActorUsername *string `json:"actor_username" db:"actor_username"`
+	Message       string  `json:"message" db:"-"`
+}
+
+// Listar notificações do usuário (unread=true para apenas não lidas)
+func GetNotifications(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	offset := (page - 1) * limit
+
+	notifications := []notificationView{}
+	err := database.DB.Select(&notifications, `
+		SELECT n.*, u.username AS actor_username
+		FROM notifications n
+		LEFT JOIN users u ON u.id = n.actor_id
+		WHERE n.user_id = $1 AND (NOT $2 OR n.is_read = false)
+		ORDER BY n.updated_at DESC
+		LIMIT $3 OFFSET $4
+	`, userID, c.QueryBool("unread", false), limit, offset)
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar notificações"})
+	}
+
+	for i := range notifications {
+		notifications[i].Message = notificationMessage(notifications[i])
+	}
+
+	return c.JSON(notifications)
+}
+
+// Quantidade de notificações não lidas
+func GetUnreadNotificationCount(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	var count int
+	err := database.DB.Get(&count, "SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND is_read = false", userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao contar notificações"})
+	}
+
+	return c.JSON(fiber.Map{"unread": count})
+}
+
+// Marcar notificação como lida
+func MarkNotificationRead(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	userID := c.Locals("user_id").(int)
+
+	result, err := database.DB.Exec("UPDATE notifications SET is_read = true WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao marcar notificação"})
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Notificação não encontrada"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Notificação marcada como lida"})
+}
+
+// Marcar todas as notificações como lidas
+func MarkAllNotificationsRead(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	_, err := database.DB.Exec("UPDATE notifications SET is_read = true WHERE user_id = $1 AND is_read = false", userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao marcar notificações"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Notificações marcadas como lidas"})
+}
+
+// Preferências de notificação do usuário (tipo -> habilitado)
+func GetNotificationPreferences(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	prefs, err := loadNotificationPreferences(uint64(userID))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar preferências"})
+	}
+
+	return c.JSON(prefs)
+}
+
+// Atualizar preferências de notificação
+func UpdateNotificationPreferences(c *fiber.Ctx) error {
+	var data map[string]bool
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "JSON inválido"})
+	}
+
+	valid := map[string]bool{}
+	for _, t := range notificationTypes {
+		valid[t] = true
+	}
+	for t := range data {
+		if !valid[t] {
+			return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("Tipo de notificação inválido: %s", t)})
+		}
+	}
+
+	userID := c.Locals("user_id").(int)
+
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar preferências"})
+	}
+	defer tx.Rollback()
+
+	for t, enabled := range data {
+		_, err := tx.Exec(`
+			INSERT INTO notification_preferences (user_id, type, enabled) VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, type) DO UPDATE SET enabled = EXCLUDED.enabled
+		`, userID, t, enabled)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar preferências"})
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar preferências"})
+	}
+
+	prefs, _ := loadNotificationPreferences(uint64(userID))
+	return c.JSON(prefs)
+}
+
+// notify registra a notificação respeitando as preferências do destinatário.
+// Notificações não lidas do mesmo tipo e post são agrupadas. Falhas são apenas
+// registradas no log para não impedir a ação que gerou o evento.
+func notify(event notificationEvent) {
+	if event.UserID == 0 || event.UserID == event.ActorID {
+		return
+	}
+
+	if err := createNotification(event); err != nil {
+		fmt.Printf("Erro ao criar notificação %s para o usuário %d: %v\n", event.Type, event.UserID, err)
+	}
+}
+
+func createNotification(event notificationEvent) error {
+	var enabled = true
+	database.DB.Get(&enabled, "SELECT enabled FROM notification_preferences WHERE user_id = $1 AND type = $2", event.UserID, event.Type)
+	if !enabled {
+		return nil
+	}
+
+	if event.QuestionID == 0 {
+		questionID, err := questionIDForPost(event.PostType, event.PostID)
+		if err != nil {
+			return err
+		}
+		event.QuestionID = questionID
+	}
+
+	groupKey := fmt.Sprintf("%s:%s:%d", event.Type, event.PostType, event.PostID)
+	now := time.Now()
+
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var notificationID uint64
+	err = tx.Get(&notificationID, `
+		INSERT INTO notifications (user_id, type, actor_id, actor_count, post_type, post_id, question_id, group_key, is_read, created_at, updated_at)
+		VALUES ($1, $2, $3, 1, $4, $5, $6, $7, false, $8, $8)
+		ON CONFLICT (user_id, group_key) WHERE is_read = false
+		DO UPDATE SET actor_id = EXCLUDED.actor_id, updated_at = EXCLUDED.updated_at
+		RETURNING id
+	`, event.UserID, event.Type, event.ActorID, event.PostType, event.PostID, event.QuestionID, groupKey, now)
+	if err != nil {
+		return err
+	}
+
+	// A mesma pessoa não conta duas vezes no agrupamento
+	_, err = tx.Exec(`
+		INSERT INTO notification_actors (notification_id, actor_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, notificationID, event.ActorID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE notifications
+		SET actor_count = (SELECT COUNT(*) FROM notification_actors WHERE notification_id = $1)
+		WHERE id = $1
+	`, notificationID)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	realtime.Publish(realtime.UserTopic(event.UserID), "notification", fiber.Map{
+		"id": notificationID, "type": event.Type, "post_type": event.PostType,
+		"post_id": event.PostID, "question_id": event.QuestionID,
+	})
+	return nil
+}
+
+func loadNotificationPreferences(userID uint64) (map[string]bool, error) {
+	prefs := map[string]bool{}
+	for _, t := range notificationTypes {
+		prefs[t] = true
+	}
+
+	var rows []struct {
+		Type    string `db:"type"`
+		Enabled bool   `db:"enabled"`
+	}
+	if err := database.DB.Select(&rows, "SELECT type, enabled FROM notification_preferences WHERE user_id = $1", userID); err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		prefs[row.Type] = row.Enabled
+	}
+	return prefs, nil
+}
+
+// notificationMessage monta o texto exibido, agrupando várias pessoas
+func notificationMessage(n notificationView) string {
+	actor := "Alguém"
+	if n.ActorUsername != nil {
+		actor = *n.ActorUsername
+	}
+
+	target := "sua pergunta"
+	switch n.PostType {
+	case "answer":
+		target = "sua resposta"
+	case "comment":
+		target = "um comentário"
+	}
+
+	switch n.Type {
+	case "answer":
+		if n.ActorCount > 1 {
+			return fmt.Sprintf("%d novas respostas na sua pergunta", n.ActorCount)
+		}
+		return fmt.Sprintf("%s respondeu sua pergunta", actor)
+	case "accepted":
+		return "Sua resposta foi aceita"
+	case "upvote":
+		if n.ActorCount > 1 {
+			return fmt.Sprintf("%d pessoas votaram positivamente em %s", n.ActorCount, target)
+		}
+		return fmt.Sprintf("%s votou positivamente em %s", actor, target)
+	case "mention":
+		return fmt.Sprintf("%s mencionou você", actor)
+	case "followed_answer":
+		if n.ActorCount > 1 {
+			return fmt.Sprintf("%d novas respostas em uma pergunta que você segue", n.ActorCount)
+		}
+		return fmt.Sprintf("%s respondeu uma pergunta que você segue", actor)
+	}
+	return ""
+}
+
+// questionIDForPost encontra a pergunta à qual o post pertence
+func questionIDForPost(postType string, postID uint64) (uint64, error) {
+	var questionID uint64
+	var err error
+
+	switch postType {
+	case "question":
+		questionID = postID
+	case "answer":
+		err = database.DB.Get(&questionID, "SELECT question_id FROM answers WHERE id = $1", postID)
+	case "comment":
+		err = database.DB.Get(&questionID, `
+			SELECT COALESCE(q.id, a.question_id)
+			FROM comments c
+			LEFT JOIN questions q ON c.post_type = 'question' AND q.id = c.post_id
+			LEFT JOIN answers a ON c.post_type = 'answer' AND a.id = c.post_id
+			WHERE c.id = $1
+		`, postID)
+	default:
+		err = fmt.Errorf("tipo de post inválido: %s", postType)
+	}
+
+	return questionID, err
+}
+
+// postOwner retorna o autor de uma pergunta ou resposta
+func postOwner(postType string, postID uint64) (uint64, error) {
+	var ownerID uint64
+	var err error
+	if postType == "question" {
+		err = database.DB.Get(&ownerID, "SELECT user_id FROM questions WHERE id = $1", postID)
+	} else {
+		err = database.DB.Get(&ownerID, "SELECT user_id FROM answers WHERE id = $1", postID)
+	}
+	return ownerID, err
+}
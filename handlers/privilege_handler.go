@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"msu-forum/database"
+	"msu-forum/models"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// privilegeStatus é o privilégio na visão de um usuário
+type privilegeStatus struct {
+	models.Privilege
+	Granted bool   `json:"granted" db:"granted"`
+	Source  string `json:"source" db:"source"` // "reputation", "override" ou "role"
+}
+
+// Privilégios do usuário atual e o próximo a ser liberado
+func GetMyPrivileges(c *fiber.Ctx) error {
+	return privilegesResponse(c, uint64(c.Locals("user_id").(int)))
+}
+
+// Privilégios de um usuário (apenas admin)
+func GetUserPrivileges(c *fiber.Ctx) error {
+	userID, err := strconv.ParseUint(c.Params("userId"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID do usuário inválido"})
+	}
+	return privilegesResponse(c, userID)
+}
+
+// Alterar a reputação mínima de um privilégio (apenas admin)
+func UpdatePrivilege(c *fiber.Ctx) error {
+	var data struct {
+		MinReputation *int32 `json:"min_reputation" validate:"required,min=0"`
+	}
+
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "JSON inválido"})
+	}
+
+	if err := Validate.Struct(data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos"})
+	}
+
+	var privilege models.Privilege
+	err := database.DB.Get(&privilege,
+		"UPDATE privileges SET min_reputation = $1 WHERE name = $2 RETURNING *",
+		*data.MinReputation, c.Params("name"))
+	if err == sql.ErrNoRows {
+		return c.Status(404).JSON(fiber.Map{"error": "Privilégio não encontrado"})
+	}
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar privilégio"})
+	}
+
+	return c.JSON(privilege)
+}
+
+// Conceder ou revogar um privilégio para um usuário, ignorando a reputação (apenas admin)
+func SetPrivilegeOverride(c *fiber.Ctx) error {
+	userID, err := strconv.ParseUint(c.Params("userId"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID do usuário inválido"})
+	}
+
+	var data struct {
+		Granted *bool `json:"granted" validate:"required"`
+	}
+
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "JSON inválido"})
+	}
+
+	if err := Validate.Struct(data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos"})
+	}
+
+	var exists bool
+	err = database.DB.Get(&exists, `
+		SELECT EXISTS(SELECT 1 FROM users WHERE id = $1) AND EXISTS(SELECT 1 FROM privileges WHERE name = $2)
+	`, userID, c.Params("name"))
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar privilégio"})
+	}
+	if !exists {
+		return c.Status(404).JSON(fiber.Map{"error": "Usuário ou privilégio não encontrado"})
+	}
+
+	_, err = database.DB.Exec(`
+		INSERT INTO user_privilege_overrides (user_id, privilege, granted, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, privilege)
+		DO UPDATE SET granted = EXCLUDED.granted, created_by = EXCLUDED.created_by, created_at = EXCLUDED.created_at
+	`, userID, c.Params("name"), *data.Granted, c.Locals("user_id").(int), time.Now())
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar privilégio"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Privilégio atualizado com sucesso"})
+}
+
+// Remover a concessão/revogação individual, voltando a valer a reputação (apenas admin)
+func DeletePrivilegeOverride(c *fiber.Ctx) error {
+	userID, err := strconv.ParseUint(c.Params("userId"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID do usuário inválido"})
+	}
+
+	_, err = database.DB.Exec("DELETE FROM user_privilege_overrides WHERE user_id = $1 AND privilege = $2", userID, c.Params("name"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao remover privilégio"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Privilégio volta a depender da reputação"})
+}
+
+// hasPrivilege verifica se o usuário autenticado tem o privilégio; retorna também a
+// reputação exigida, para a mensagem de erro. Moderadores têm todos os privilégios.
+// Erros do banco negam o acesso e devem virar 500.
+func hasPrivilege(c *fiber.Ctx, name string) (bool, int32, error) {
+	var status struct {
+		Granted       bool  `db:"granted"`
+		MinReputation int32 `db:"min_reputation"`
+	}
+	err := database.DB.Get(&status, `
+		SELECT COALESCE(o.granted, u.reputation >= p.min_reputation) AS granted, p.min_reputation
+		FROM privileges p
+		JOIN users u ON u.id = $1
+		LEFT JOIN user_privilege_overrides o ON o.user_id = u.id AND o.privilege = p.name
+		WHERE p.name = $2
+	`, c.Locals("user_id").(int), name)
+	if err == sql.ErrNoRows {
+		// Privilégio não configurado não bloqueia ninguém
+		return true, 0, nil
+	}
+	if err != nil {
+		fmt.Printf("Erro ao verificar privilégio %s: %v\n", name, err)
+		return false, 0, err
+	}
+
+	if isModerator(c.Locals("role").(string)) {
+		return true, status.MinReputation, nil
+	}
+	return status.Granted, status.MinReputation, nil
+}
+
+// privilegeDenied monta a resposta 403 padrão para privilégio ausente
+func privilegeDenied(c *fiber.Ctx, name string, required int32) error {
+	return c.Status(403).JSON(fiber.Map{
+		"error":               fmt.Sprintf("Reputação insuficiente: são necessários %d pontos", required),
+		"privilege":           name,
+		"required_reputation": required,
+	})
+}
+
+func privilegesResponse(c *fiber.Ctx, userID uint64) error {
+	var user struct {
+		Reputation int32  `db:"reputation"`
+		Role       string `db:"role"`
+	}
+	if err := database.DB.Get(&user, "SELECT reputation, role FROM users WHERE id = $1", userID); err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Usuário não encontrado"})
+	}
+
+	privileges := []privilegeStatus{}
+	err := database.DB.Select(&privileges, `
+		SELECT p.*,
+		       $2 OR COALESCE(o.granted, u.reputation >= p.min_reputation) AS granted,
+		       CASE WHEN $2 THEN 'role'
+		            WHEN o.granted IS NOT NULL THEN 'override'
+		            ELSE 'reputation' END AS source
+		FROM privileges p
+		JOIN users u ON u.id = $1
+		LEFT JOIN user_privilege_overrides o ON o.user_id = u.id AND o.privilege = p.name
+		ORDER BY p.min_reputation, p.name
+	`, userID, isModerator(user.Role))
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar privilégios"})
+	}
+
+	// Próximo privilégio liberado por reputação
+	var next fiber.Map
+	for _, p := range privileges {
+		if !p.Granted && p.Source == "reputation" {
+			next = fiber.Map{"name": p.Name, "min_reputation": p.MinReputation, "remaining": p.MinReputation - user.Reputation}
+			break
+		}
+	}
+
+	return c.JSON(fiber.Map{"reputation": user.Reputation, "privileges": privileges, "next": next})
+}
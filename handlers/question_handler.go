@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"msu-forum/database"
 	"msu-forum/models"
+	"msu-forum/realtime"
+	"msu-forum/render"
 	"strconv"
 	"time"
 
@@ -29,14 +31,26 @@ func CreateQuestion(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(int)
 	now := time.Now()
 
+	bodyHTML, err := render.Markdown(data.Body)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Erro ao processar o conteúdo"})
+	}
+
+	// Sinônimos viram a tag principal; tags novas exigem o privilégio create_tags
+	tags, status, err := resolveTags(c, data.Tags)
+	if err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+	data.Tags = tagNames(tags)
+
 	// Inserir pergunta
-	query := `INSERT INTO questions (user_id, title, body, votes, view_count, answer_count, is_solved, created_at, updated_at) 
-			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`
+	query := `INSERT INTO questions (user_id, title, body, body_html, votes, view_count, answer_count, is_solved, created_at, updated_at) 
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`
 
 	var questionID uint64
-	err := database.DB.QueryRow(
+	err = database.DB.QueryRow(
 		query,
-		userID, data.Title, data.Body, 0, 0, 0, false, now, now,
+		userID, data.Title, data.Body, bodyHTML, 0, 0, 0, false, now, now,
 	).Scan(&questionID)
 
 	if err != nil {
@@ -45,24 +59,32 @@ func CreateQuestion(c *fiber.Ctx) error {
 	}
 
 	// Inserir tags se fornecidas
-	if len(data.Tags) > 0 {
-		for _, tagName := range data.Tags {
-			// Verificar se tag existe, se não, criar
-			var tagID uint64
-			err := database.DB.QueryRow("SELECT id FROM tags WHERE name = $1", tagName).Scan(&tagID)
-			if err != nil {
-				return c.Status(500).JSON(fiber.Map{"error": "Tag não encontrada"})
-			} else {
-				// Atualizar contador de uso
-				database.DB.Exec("UPDATE tags SET usage_count = usage_count + 1 WHERE id = $1", tagID)
-			}
-
-			// Inserir relação question_tag
-			database.DB.Exec("INSERT INTO question_tags (question_id, tag_id) VALUES ($1, $2)", questionID, tagID)
-		}
+	var tagIDs []uint64
+	for _, tag := range tags {
+		tagIDs = append(tagIDs, tag.ID)
 	}
+	if err := setQuestionTags(questionID, tagIDs); err != nil {
+		fmt.Printf("Erro ao salvar tags: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao salvar tags"})
+	}
+
+	clearDraft(userID, "question", 0)
+	follow(uint64(userID), "question", questionID)
+	mentions := recordMentions("question", questionID, userID, data.Body)
+	linkAttachments("question", questionID, data.Body)
 
-	return c.Status(201).JSON(fiber.Map{"id": questionID, "message": "Pergunta criada com sucesso"})
+	for _, tagID := range tagIDs {
+		realtime.Publish(realtime.TagTopic(tagID), "question.created", fiber.Map{
+			"id": questionID, "title": data.Title, "user_id": userID, "tag_id": tagID,
+		})
+	}
+
+	dispatchWebhook("question.created", data.Tags, fiber.Map{
+		"id": questionID, "title": data.Title, "user_id": userID, "tags": data.Tags, "url": questionURL(questionID),
+	})
+	queueBadgeCheck(uint64(userID))
+
+	return c.Status(201).JSON(fiber.Map{"id": questionID, "message": "Pergunta criada com sucesso", "mentions": mentions})
 }
 
 // Listar perguntas
@@ -74,12 +96,17 @@ func GetQuestions(c *fiber.Ctx) error {
 	fmt.Printf("Executando query com limit: %d, offset: %d\n", limit, offset)
 	query := `
 		SELECT q.*, u.username, u.avatar_url,
-		       COUNT(DISTINCT a.id) as answer_count
+		       COUNT(DISTINCT a.id) as answer_count,
+		       b.amount as bounty_amount, b.expires_at as bounty_expires_at,
+		       EXISTS(SELECT 1 FROM question_pins p WHERE p.question_id = q.id AND p.tag_id IS NULL) as is_pinned,
+		       COALESCE(q.featured_until > NOW(), false) as is_featured
 		FROM questions q
 		LEFT JOIN users u ON q.user_id = u.id
-		LEFT JOIN answers a ON q.id = a.question_id
-		GROUP BY q.id, u.username, u.avatar_url
-		ORDER BY q.created_at DESC
+		LEFT JOIN answers a ON q.id = a.question_id AND a.deleted_at IS NULL
+		LEFT JOIN bounties b ON b.question_id = q.id AND b.status = 'active'
+		WHERE q.deleted_at IS NULL AND ($3 OR q.closed_at IS NULL)
+		GROUP BY q.id, u.username, u.avatar_url, b.amount, b.expires_at
+		ORDER BY is_pinned DESC, is_featured DESC, q.created_at DESC
 		LIMIT $1 OFFSET $2
 	`
 
@@ -89,7 +116,7 @@ func GetQuestions(c *fiber.Ctx) error {
 		AvatarURL string `json:"avatar_url" db:"avatar_url"`
 	}
 
-	err := database.DB.Select(&questions, query, limit, offset)
+	err := database.DB.Select(&questions, query, limit, offset, includeClosed(c))
 	if err != nil {
 		fmt.Printf("Erro no banco: %v\n", err)
 		return c.Status(500).JSON(fiber.Map{
@@ -109,20 +136,24 @@ func GetQuestion(c *fiber.Ctx) error {
 	}
 
 	// Incrementar contador de visualizações
-	database.DB.Exec("UPDATE questions SET view_count = view_count + 1 WHERE id = $1", id)
+	database.DB.Exec("UPDATE questions SET view_count = view_count + 1 WHERE id = $1 AND deleted_at IS NULL", id)
 
 	// Buscar pergunta com usuário
 	var question struct {
 		models.Question
-		Username  string `json:"username" db:"username"`
-		AvatarURL string `json:"avatar_url" db:"avatar_url"`
+		Username  string          `json:"username" db:"username"`
+		AvatarURL string          `json:"avatar_url" db:"avatar_url"`
+		Answers   []answerView    `json:"answers" db:"-"`
+		Mentions  []mentionRef    `json:"mentions" db:"-"`
+		Reactions []reactionCount `json:"reactions" db:"-"`
+		commentThread
 	}
 
 	err = database.DB.Get(&question, `
 		SELECT q.*, u.username, u.avatar_url
 		FROM questions q
 		LEFT JOIN users u ON q.user_id = u.id
-		WHERE q.id = $1
+		WHERE q.id = $1 AND q.deleted_at IS NULL
 	`, id)
 
 	if err != nil {
@@ -139,15 +170,43 @@ func GetQuestion(c *fiber.Ctx) error {
 	`, id)
 	question.Tags = tags
 
-	// Buscar respostas
-	var answers []models.Answer
-	database.DB.Select(&answers, `
-		SELECT a.*, u.username, u.avatar_url
-		FROM answers a
-		LEFT JOIN users u ON a.user_id = u.id
-		WHERE a.question_id = $1
-		ORDER BY a.is_accepted DESC, a.votes DESC, a.created_at ASC
-	`, id)
+	// Buscar respostas (rota pública: sem voto do visitante)
+	options, err := parseAnswerListOptions(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	answers, err := selectAnswers(id, 0, options, 0, 0)
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar respostas"})
+	}
+	// Buscar comentários da pergunta e das respostas
+	threads, err := loadCommentThreads("question", []uint64{id})
+	if err != nil {
+		fmt.Printf("Erro ao buscar comentários: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar comentários"})
+	}
+	question.commentThread = threads[id]
+
+	mentions, err := loadMentions("question", []uint64{id})
+	if err != nil {
+		fmt.Printf("Erro ao buscar menções: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar menções"})
+	}
+	question.Mentions = mentions[id]
+
+	reactions, err := loadReactions("question", []uint64{id}, 0)
+	if err != nil {
+		fmt.Printf("Erro ao buscar reações: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar reações"})
+	}
+	question.Reactions = reactions[id]
+
+	if err := attachAnswerComments(answers, 0); err != nil {
+		fmt.Printf("Erro ao buscar comentários: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar comentários"})
+	}
 	question.Answers = answers
 
 	return c.JSON(question)
@@ -178,26 +237,66 @@ func UpdateQuestion(c *fiber.Ctx) error {
 
 	// Verificar se a pergunta pertence ao usuário
 	var question models.Question
-	err = database.DB.Get(&question, "SELECT user_id FROM questions WHERE id = $1", id)
+	err = database.DB.Get(&question, "SELECT user_id, locked_at FROM questions WHERE id = $1 AND deleted_at IS NULL", id)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Pergunta não encontrada"})
 	}
 
 	if uint64(userID) != question.UserID {
-		return c.Status(403).JSON(fiber.Map{"error": "Sem permissão para editar esta pergunta"})
+		ok, _, err := hasPrivilege(c, "edit_posts")
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao verificar privilégios"})
+		}
+		if !ok {
+			return c.Status(403).JSON(fiber.Map{"error": "Sem permissão para editar esta pergunta"})
+		}
+	}
+
+	if question.LockedAt != nil {
+		return c.Status(403).JSON(fiber.Map{"error": "Pergunta trancada não pode ser editada"})
+	}
+
+	bodyHTML, err := render.Markdown(data.Body)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Erro ao processar o conteúdo"})
+	}
+
+	// Tags omitidas continuam as mesmas
+	var tags []models.Tag
+	if data.Tags != nil {
+		var status int
+		tags, status, err = resolveTags(c, data.Tags)
+		if err != nil {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
 	}
 
 	// Atualizar pergunta
 	_, err = database.DB.Exec(
-		"UPDATE questions SET title = $1, body = $2, updated_at = $3 WHERE id = $4",
-		data.Title, data.Body, time.Now(), id,
+		"UPDATE questions SET title = $1, body = $2, body_html = $3, updated_at = $4, edited_at = $4 WHERE id = $5",
+		data.Title, data.Body, bodyHTML, time.Now(), id,
 	)
 
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar pergunta"})
 	}
 
-	return c.JSON(fiber.Map{"message": "Pergunta atualizada com sucesso"})
+	if data.Tags != nil {
+		tagIDs := make([]uint64, len(tags))
+		for i, tag := range tags {
+			tagIDs[i] = tag.ID
+		}
+		if err := setQuestionTags(id, tagIDs); err != nil {
+			fmt.Printf("Erro ao salvar tags: %v\n", err)
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao salvar tags"})
+		}
+	}
+
+	clearDraft(userID, "edit_question", id)
+	mentions := recordMentions("question", id, int(question.UserID), data.Body)
+	linkAttachments("question", id, data.Body)
+
+	return c.JSON(fiber.Map{"message": "Pergunta atualizada com sucesso", "mentions": mentions})
 }
 
 // Deletar pergunta
@@ -212,7 +311,7 @@ func DeleteQuestion(c *fiber.Ctx) error {
 
 	// Verificar se a pergunta pertence ao usuário ou se é admin
 	var question models.Question
-	err = database.DB.Get(&question, "SELECT user_id FROM questions WHERE id = $1", id)
+	err = database.DB.Get(&question, "SELECT user_id FROM questions WHERE id = $1 AND deleted_at IS NULL", id)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Pergunta não encontrada"})
 	}
@@ -221,8 +320,12 @@ func DeleteQuestion(c *fiber.Ctx) error {
 		return c.Status(403).JSON(fiber.Map{"error": "Sem permissão para deletar esta pergunta"})
 	}
 
-	// Deletar pergunta (cascade irá deletar respostas e votos)
-	_, err = database.DB.Exec("DELETE FROM questions WHERE id = $1", id)
+	// Exclusão lógica; respostas ficam ocultas junto com a pergunta e a
+	// remoção definitiva (com votos) fica com o job de retenção
+	_, err = database.DB.Exec(
+		"UPDATE questions SET deleted_at = $1, deleted_by = $2 WHERE id = $3",
+		time.Now(), userID, id,
+	)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar pergunta"})
 	}
@@ -231,13 +334,22 @@ func DeleteQuestion(c *fiber.Ctx) error {
 }
 
 
-// Buscar perguntas por título ou corpo
+// Buscar perguntas por título ou corpo (?tag= filtra pela tag ou por um sinônimo dela)
 func SearchQuestions(c *fiber.Ctx) error {
 	query := c.Query("q")
 	if query == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "Parâmetro de busca é obrigatório"})
 	}
 
+	var tagID uint64
+	if c.Query("tag") != "" {
+		tag, err := findTag(c.Query("tag"))
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{"error": "Tag não encontrada"})
+		}
+		tagID = tag.ID
+	}
+
 	var questions []struct {
 		models.Question
 		Username  string `json:"username" db:"username"`
@@ -250,10 +362,12 @@ func SearchQuestions(c *fiber.Ctx) error {
 		       GREATEST(similarity(q.title, $1), similarity(q.body, $1)) AS similarity
 		FROM questions q
 		LEFT JOIN users u ON q.user_id = u.id
-		WHERE q.title ILIKE '%' || $1 || '%' OR q.body ILIKE '%' || $1 || '%'
+		WHERE (q.title ILIKE '%' || $1 || '%' OR q.body ILIKE '%' || $1 || '%')
+		  AND q.deleted_at IS NULL AND ($2 OR q.closed_at IS NULL)
+		  AND ($3 = 0 OR EXISTS(SELECT 1 FROM question_tags qt WHERE qt.question_id = q.id AND qt.tag_id = $3))
 		ORDER BY similarity DESC
 		LIMIT 20
-	`, query)
+	`, query, includeClosed(c), tagID)
 
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar perguntas"})
@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"msu-forum/database"
+	"msu-forum/models"
+	"msu-forum/realtime"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/lib/pq"
+)
+
+// Códigos de reação: minúsculas, números e sublinhado
+var reactionNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// reactionCount é o total de uma reação num post; Reacted indica se o usuário
+// atual está entre quem reagiu
+type reactionCount struct {
+	PostID   uint64  `json:"-" db:"post_id"`
+	Reaction string  `json:"reaction" db:"reaction"`
+	Emoji    *string `json:"emoji" db:"emoji"`
+	ImageURL *string `json:"image_url" db:"image_url"`
+	Count    int     `json:"count" db:"count"`
+	Reacted  bool    `json:"reacted" db:"reacted"`
+}
+
+type reactionTypeInput struct {
+	Emoji    *string `json:"emoji" validate:"omitempty,max=16"`
+	ImageURL *string `json:"image_url" validate:"omitempty,url,max=500"`
+	IsActive *bool   `json:"is_active"`
+	Position *int32  `json:"position"`
+}
+
+// Listar as reações disponíveis
+func GetReactionTypes(c *fiber.Ctx) error {
+	types := []models.ReactionType{}
+	err := database.DB.Select(&types, "SELECT * FROM reaction_types WHERE is_active = true ORDER BY position, name")
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar reações"})
+	}
+
+	return c.JSON(types)
+}
+
+// Listar todas as reações, incluindo as desativadas (apenas admin)
+func GetAllReactionTypes(c *fiber.Ctx) error {
+	types := []models.ReactionType{}
+	err := database.DB.Select(&types, "SELECT * FROM reaction_types ORDER BY position, name")
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar reações"})
+	}
+
+	return c.JSON(types)
+}
+
+// Criar reação, com emoji ou imagem personalizada (apenas admin)
+func CreateReactionType(c *fiber.Ctx) error {
+	var data struct {
+		Name string `json:"name" validate:"required,max=30"`
+		reactionTypeInput
+	}
+
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "JSON inválido"})
+	}
+
+	if err := Validate.Struct(data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	if !reactionNamePattern.MatchString(data.Name) {
+		return c.Status(400).JSON(fiber.Map{"error": "name deve conter apenas letras minúsculas, números e _"})
+	}
+
+	if data.Emoji == nil && data.ImageURL == nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Informe emoji ou image_url"})
+	}
+
+	isActive := data.IsActive == nil || *data.IsActive
+	var position int32
+	if data.Position != nil {
+		position = *data.Position
+	}
+
+	var reactionType models.ReactionType
+	err := database.DB.Get(&reactionType, `
+		INSERT INTO reaction_types (name, emoji, image_url, is_active, position, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (name) DO NOTHING
+		RETURNING *
+	`, data.Name, data.Emoji, data.ImageURL, isActive, position, c.Locals("user_id").(int), time.Now())
+	if err == sql.ErrNoRows {
+		return c.Status(409).JSON(fiber.Map{"error": "Já existe uma reação com esse nome"})
+	}
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar reação"})
+	}
+
+	return c.Status(201).JSON(reactionType)
+}
+
+// Atualizar reação; campos omitidos não mudam (apenas admin)
+func UpdateReactionType(c *fiber.Ctx) error {
+	var data reactionTypeInput
+
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "JSON inválido"})
+	}
+
+	if err := Validate.Struct(data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	var reactionType models.ReactionType
+	err := database.DB.Get(&reactionType, `
+		UPDATE reaction_types
+		SET emoji = COALESCE($1, emoji), image_url = COALESCE($2, image_url),
+		    is_active = COALESCE($3, is_active), position = COALESCE($4, position)
+		WHERE name = $5
+		RETURNING *
+	`, data.Emoji, data.ImageURL, data.IsActive, data.Position, c.Params("name"))
+	if err == sql.ErrNoRows {
+		return c.Status(404).JSON(fiber.Map{"error": "Reação não encontrada"})
+	}
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar reação"})
+	}
+
+	return c.JSON(reactionType)
+}
+
+// Deletar reação junto com os usos dela nos posts (apenas admin); para manter o
+// histórico, desative com is_active=false
+func DeleteReactionType(c *fiber.Ctx) error {
+	result, err := database.DB.Exec("DELETE FROM reaction_types WHERE name = $1", c.Params("name"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar reação"})
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Reação não encontrada"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Reação deletada com sucesso"})
+}
+
+// Reagir a um post; repetir a mesma reação a remove
+func ToggleReaction(c *fiber.Ctx) error {
+	var data struct {
+		PostType string `json:"post_type" validate:"required,oneof=question answer"`
+		PostID   uint64 `json:"post_id" validate:"required"`
+		Reaction string `json:"reaction" validate:"required,max=30"`
+	}
+
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "JSON inválido"})
+	}
+
+	if err := Validate.Struct(data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	var active bool
+	err := database.DB.Get(&active, "SELECT is_active FROM reaction_types WHERE name = $1", data.Reaction)
+	if err == sql.ErrNoRows || (err == nil && !active) {
+		return c.Status(400).JSON(fiber.Map{"error": "Reação inválida"})
+	}
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao registrar reação"})
+	}
+
+	locked, err := isPostLocked(data.PostType, data.PostID)
+	if err == sql.ErrNoRows {
+		return c.Status(404).JSON(fiber.Map{"error": "Post não encontrado"})
+	}
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao registrar reação"})
+	}
+
+	if locked {
+		return c.Status(403).JSON(fiber.Map{"error": "Pergunta trancada não aceita reações"})
+	}
+
+	userID := c.Locals("user_id").(int)
+
+	result, err := database.DB.Exec(`
+		DELETE FROM post_reactions
+		WHERE post_type = $1 AND post_id = $2 AND reaction = $3 AND user_id = $4
+	`, data.PostType, data.PostID, data.Reaction, userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao registrar reação"})
+	}
+
+	reacted := false
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		_, err = database.DB.Exec(`
+			INSERT INTO post_reactions (user_id, post_type, post_id, reaction, created_at)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT DO NOTHING
+		`, userID, data.PostType, data.PostID, data.Reaction, time.Now())
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao registrar reação"})
+		}
+		reacted = true
+	}
+
+	counts, err := loadReactions(data.PostType, []uint64{data.PostID}, userID)
+	if err != nil {
+		fmt.Printf("Erro ao buscar reações: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar reações"})
+	}
+
+	publishReactions(data.PostType, data.PostID)
+
+	return c.JSON(fiber.Map{"reaction": data.Reaction, "reacted": reacted, "reactions": counts[data.PostID]})
+}
+
+// Listar quem reagiu a um post (?post_type=question|answer&post_id=N, reaction opcional)
+func GetReactionUsers(c *fiber.Ctx) error {
+	postType := c.Query("post_type")
+	if postType != "question" && postType != "answer" {
+		return c.Status(400).JSON(fiber.Map{"error": "post_type deve ser 'question' ou 'answer'"})
+	}
+
+	postID, err := strconv.ParseUint(c.Query("post_id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "post_id inválido"})
+	}
+
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "50"))
+	offset := (page - 1) * limit
+
+	users := []struct {
+		models.PostReaction
+		Username  string  `json:"username" db:"username"`
+		AvatarURL *string `json:"avatar_url" db:"avatar_url"`
+	}{}
+	err = database.DB.Select(&users, `
+		SELECT r.*, u.username, u.avatar_url
+		FROM post_reactions r
+		JOIN users u ON u.id = r.user_id
+		WHERE r.post_type = $1 AND r.post_id = $2 AND ($3 = '' OR r.reaction = $3)
+		ORDER BY r.created_at DESC
+		LIMIT $4 OFFSET $5
+	`, postType, postID, c.Query("reaction"), limit, offset)
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar reações"})
+	}
+
+	return c.JSON(users)
+}
+
+// loadReactions agrega as reações ativas de cada post; viewerID 0 para visitantes
+func loadReactions(postType string, postIDs []uint64, viewerID int) (map[uint64][]reactionCount, error) {
+	result := make(map[uint64][]reactionCount, len(postIDs))
+	for _, id := range postIDs {
+		result[id] = []reactionCount{}
+	}
+	if len(postIDs) == 0 {
+		return result, nil
+	}
+
+	var counts []reactionCount
+	err := database.DB.Select(&counts, `
+		SELECT r.post_id, r.reaction, t.emoji, t.image_url,
+		       COUNT(*) AS count, BOOL_OR(r.user_id = $3) AS reacted
+		FROM post_reactions r
+		JOIN reaction_types t ON t.name = r.reaction AND t.is_active = true
+		WHERE r.post_type = $1 AND r.post_id = ANY($2)
+		GROUP BY r.post_id, r.reaction, t.emoji, t.image_url, t.position
+		ORDER BY t.position, r.reaction
+	`, postType, pq.Array(postIDs), viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, count := range counts {
+		result[count.PostID] = append(result[count.PostID], count)
+	}
+	return result, nil
+}
+
+// publishReactions envia os totais atualizados para quem acompanha a pergunta
+func publishReactions(postType string, postID uint64) {
+	questionID, err := questionIDForPost(postType, postID)
+	if err != nil {
+		return
+	}
+
+	counts, err := loadReactions(postType, []uint64{postID}, 0)
+	if err != nil {
+		return
+	}
+
+	realtime.Publish(realtime.QuestionTopic(questionID), "reaction.updated", fiber.Map{
+		"post_type": postType, "post_id": postID, "reactions": counts[postID],
+	})
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"msu-forum/realtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	maxRealtimeTopics = 20
+	realtimeHeartbeat = 20 * time.Second
+)
+
+// Stream de eventos em tempo real (Server-Sent Events).
+// Tópicos em ?topics=question:12,tag:3,notifications
+func StreamEvents(c *fiber.Ctx) error {
+	userID := uint64(c.Locals("user_id").(int))
+
+	topics, err := parseRealtimeTopics(c.Query("topics"), userID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	sub := realtime.Default.Subscribe(topics)
+
+	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+		defer realtime.Default.Unsubscribe(sub)
+
+		heartbeat := time.NewTicker(realtimeHeartbeat)
+		defer heartbeat.Stop()
+
+		fmt.Fprintf(w, "event: ready\ndata: {\"topics\":%q}\n\n", strings.Join(topics, ","))
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		for {
+			select {
+			case payload, ok := <-sub.C:
+				if !ok {
+					// Cliente lento demais: avisar para reconectar e recarregar o estado
+					if sub.Lagged() {
+						fmt.Fprint(w, "event: lagged\ndata: {}\n\n")
+						w.Flush()
+					}
+					return
+				}
+				fmt.Fprintf(w, "data: %s\n\n", payload)
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": ping\n\n")
+			}
+
+			// Falha ao enviar indica que o cliente desconectou
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}))
+
+	return nil
+}
+
+// parseRealtimeTopics valida os tópicos pedidos; "notifications" vira o tópico
+// privado do próprio usuário
+func parseRealtimeTopics(raw string, userID uint64) ([]string, error) {
+	topics := []string{}
+	seen := map[string]bool{}
+
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		var topic string
+		if item == "notifications" {
+			topic = realtime.UserTopic(userID)
+		} else {
+			kind, idStr, found := strings.Cut(item, ":")
+			id, err := strconv.ParseUint(idStr, 10, 64)
+			if !found || err != nil {
+				return nil, fmt.Errorf("Tópico inválido: %s", item)
+			}
+
+			switch kind {
+			case "question":
+				topic = realtime.QuestionTopic(id)
+			case "tag":
+				topic = realtime.TagTopic(id)
+			default:
+				return nil, fmt.Errorf("Tópico inválido: %s", item)
+			}
+		}
+
+		if !seen[topic] {
+			seen[topic] = true
+			topics = append(topics, topic)
+		}
+	}
+
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("Informe ao menos um tópico")
+	}
+	if len(topics) > maxRealtimeTopics {
+		return nil, fmt.Errorf("Máximo de %d tópicos por conexão", maxRealtimeTopics)
+	}
+
+	return topics, nil
+}
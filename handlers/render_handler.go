@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"fmt"
+	"msu-forum/database"
+	"msu-forum/render"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Quantidade de posts renderizados por consulta
+const renderBatchSize = 500
+
+// Renderizar novamente o HTML de todos os posts (apenas admin).
+// Usado quando as regras de Markdown ou do sanitizador mudam.
+func RerenderPosts(c *fiber.Ctx) error {
+	questions, err := rerenderTable("questions")
+	if err != nil {
+		fmt.Printf("Erro ao renderizar perguntas: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao renderizar perguntas"})
+	}
+
+	answers, err := rerenderTable("answers")
+	if err != nil {
+		fmt.Printf("Erro ao renderizar respostas: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao renderizar respostas"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":   "Posts renderizados com sucesso",
+		"questions": questions,
+		"answers":   answers,
+	})
+}
+
+// rerenderTable percorre a tabela em lotes por ID e atualiza body_html quando mudar.
+// table vem sempre de constantes internas, nunca da requisição.
+func rerenderTable(table string) (int, error) {
+	var lastID uint64
+	updated := 0
+
+	for {
+		var posts []struct {
+			ID       uint64 `db:"id"`
+			Body     string `db:"body"`
+			BodyHTML string `db:"body_html"`
+		}
+
+		err := database.DB.Select(&posts,
+			"SELECT id, body, body_html FROM "+table+" WHERE id > $1 ORDER BY id LIMIT $2",
+			lastID, renderBatchSize)
+		if err != nil {
+			return updated, err
+		}
+		if len(posts) == 0 {
+			return updated, nil
+		}
+
+		for _, post := range posts {
+			lastID = post.ID
+
+			html, err := render.Markdown(post.Body)
+			if err != nil {
+				return updated, fmt.Errorf("post %d: %w", post.ID, err)
+			}
+			if html == post.BodyHTML {
+				continue
+			}
+
+			if _, err := database.DB.Exec("UPDATE "+table+" SET body_html = $1 WHERE id = $2", html, post.ID); err != nil {
+				return updated, err
+			}
+			updated++
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"fmt"
+	"msu-forum/database"
+	"msu-forum/models"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Pontos por voto; ganhos com votos positivos são limitados por dia
+const (
+	questionUpvoteReputation = 5
+	answerUpvoteReputation   = 10
+	downvoteReceivedPenalty  = -2
+	downvoteGivenPenalty     = -1
+	dailyReputationCap       = 200
+)
+
+// Motivos de eventos gerados por votos, desfeitos juntos quando o voto muda
+var voteReputationReasons = []string{"upvote", "downvote", "downvote_given", "vote_reversal"}
+
+// reputationChange é um lançamento no histórico de reputação
+type reputationChange struct {
+	UserID   uint64
+	Delta    int
+	Reason   string
+	PostType string // opcional
+	PostID   uint64 // opcional
+	ActorID  uint64 // opcional
+}
+
+// Histórico de reputação de um usuário (público): sem quem votou e sem os votos
+// negativos dados pelo usuário
+func GetUserReputation(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	return reputationResponse(c, id, false)
+}
+
+// Histórico completo de reputação do usuário atual
+func GetMyReputation(c *fiber.Ctx) error {
+	return reputationResponse(c, uint64(c.Locals("user_id").(int)), true)
+}
+
+// Histórico completo de reputação de um usuário (Admin e Moderator)
+func GetUserReputationHistory(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	return reputationResponse(c, id, true)
+}
+
+// reputationResponse lista o histórico; full inclui os votantes e os votos negativos dados
+func reputationResponse(c *fiber.Ctx, id uint64, full bool) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	offset := (page - 1) * limit
+
+	var reputation int
+	if err := database.DB.Get(&reputation, "SELECT reputation FROM users WHERE id = $1", id); err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Usuário não encontrado"})
+	}
+
+	events := []struct {
+		models.ReputationEvent
+		QuestionID *uint64 `json:"question_id" db:"question_id"`
+		Title      *string `json:"title" db:"title"`
+	}{}
+	// Eventos de voto em que o próprio usuário é o ator são os votos negativos que ele deu
+	err := database.DB.Select(&events, `
+		SELECT e.id, e.user_id, e.delta, e.reason, e.post_type, e.post_id, e.created_at,
+		       CASE WHEN $4 OR e.reason <> ALL($5) THEN e.actor_id END AS actor_id,
+		       q.id AS question_id, q.title
+		FROM reputation_events e
+		LEFT JOIN answers a ON e.post_type = 'answer' AND a.id = e.post_id
+		LEFT JOIN questions q ON q.deleted_at IS NULL
+		     AND q.id = CASE WHEN e.post_type = 'answer' THEN a.question_id ELSE e.post_id END
+		WHERE e.user_id = $1
+		  AND ($4 OR e.reason <> ALL($5) OR e.actor_id IS DISTINCT FROM e.user_id)
+		ORDER BY e.created_at DESC, e.id DESC
+		LIMIT $2 OFFSET $3
+	`, id, limit, offset, full, pq.StringArray(voteReputationReasons))
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar histórico de reputação"})
+	}
+
+	return c.JSON(fiber.Map{"user_id": id, "reputation": reputation, "events": events})
+}
+
+// Recalcular a reputação de todos os usuários a partir dos votos, respostas
+// aceitas e recompensas (apenas admin). O histórico atual é descartado e refeito:
+// reversões e eventos sem origem nessas tabelas deixam de existir.
+func RecomputeReputation(c *fiber.Ctx) error {
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao recalcular reputação"})
+	}
+	defer tx.Rollback()
+
+	// Impede votos e aceites concorrentes de lançar eventos durante o recálculo
+	if _, err := tx.Exec("LOCK TABLE reputation_events IN EXCLUSIVE MODE"); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao recalcular reputação"})
+	}
+
+	for _, step := range recomputeSteps {
+		if _, err := tx.Exec(step.query, step.args...); err != nil {
+			fmt.Printf("Erro ao recalcular reputação: %v\n", err)
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao recalcular reputação"})
+		}
+	}
+
+	result, err := tx.Exec(`
+		UPDATE users u
+		SET reputation = COALESCE((SELECT SUM(delta) FROM reputation_events e WHERE e.user_id = u.id), 0)
+	`)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao recalcular reputação"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao recalcular reputação"})
+	}
+
+	users, _ := result.RowsAffected()
+	return c.JSON(fiber.Map{"message": "Reputação recalculada com sucesso", "users": users})
+}
+
+// Votos com o autor do post votado; votos no próprio post não contam
+const votesWithOwner = `
+	WITH owned AS (
+		SELECT v.id, v.user_id AS voter_id, v.post_type, v.post_id, v.type, v.created_at,
+		       COALESCE(q.user_id, a.user_id) AS owner_id
+		FROM votes v
+		LEFT JOIN questions q ON v.post_type = 'question' AND q.id = v.post_id
+		LEFT JOIN answers a ON v.post_type = 'answer' AND a.id = v.post_id
+		WHERE COALESCE(q.user_id, a.user_id) <> v.user_id
+	)`
+
+var recomputeSteps = []struct {
+	query string
+	args  []interface{}
+}{
+	{query: "DELETE FROM reputation_events"},
+	// Votos positivos, aplicando o limite diário na ordem em que foram dados
+	{query: votesWithOwner + `,
+		running AS (
+			SELECT *, SUM(delta) OVER (PARTITION BY owner_id, created_at::date ORDER BY created_at, id) AS total
+			FROM (
+				SELECT *, CASE WHEN post_type = 'question' THEN $1::int ELSE $2::int END AS delta
+				FROM owned WHERE type = 1
+			) up
+		)
+		INSERT INTO reputation_events (user_id, delta, reason, post_type, post_id, actor_id, created_at)
+		SELECT owner_id, LEAST(total, $3::int) - LEAST(total - delta, $3::int), 'upvote', post_type, post_id, voter_id, created_at
+		FROM running
+		WHERE LEAST(total, $3::int) - LEAST(total - delta, $3::int) > 0
+	`, args: []interface{}{questionUpvoteReputation, answerUpvoteReputation, dailyReputationCap}},
+	{query: votesWithOwner + `
+		INSERT INTO reputation_events (user_id, delta, reason, post_type, post_id, actor_id, created_at)
+		SELECT owner_id, $1::int, 'downvote', post_type, post_id, voter_id, created_at
+		FROM owned WHERE type = -1
+	`, args: []interface{}{downvoteReceivedPenalty}},
+	{query: votesWithOwner + `
+		INSERT INTO reputation_events (user_id, delta, reason, post_type, post_id, actor_id, created_at)
+		SELECT voter_id, $1::int, 'downvote_given', post_type, post_id, voter_id, created_at
+		FROM owned WHERE type = -1 AND post_type = 'answer'
+	`, args: []interface{}{downvoteGivenPenalty}},
+	{query: `
+		INSERT INTO reputation_events (user_id, delta, reason, post_type, post_id, actor_id, created_at)
+		SELECT a.user_id, $1::int, 'accepted', 'answer', a.id, q.user_id, COALESCE(a.accepted_at, a.created_at)
+		FROM answers a JOIN questions q ON q.id = a.question_id
+		WHERE a.is_accepted = true AND a.user_id <> q.user_id
+		UNION ALL
+		SELECT q.user_id, $2::int, 'accept_given', 'answer', a.id, q.user_id, COALESCE(a.accepted_at, a.created_at)
+		FROM answers a JOIN questions q ON q.id = a.question_id
+		WHERE a.is_accepted = true AND a.user_id <> q.user_id
+	`, args: []interface{}{acceptedAnswerReputation, acceptorReputation}},
+	{query: `
+		INSERT INTO reputation_events (user_id, delta, reason, post_type, post_id, actor_id, created_at)
+		SELECT b.user_id, -b.amount, 'bounty_offered', 'question', b.question_id, b.user_id, b.created_at
+		FROM bounties b
+		UNION ALL
+		SELECT a.user_id, b.amount, 'bounty_awarded', 'answer', a.id, b.user_id, b.awarded_at
+		FROM bounties b JOIN answers a ON a.id = b.awarded_answer_id
+		WHERE b.status = 'awarded'
+	`},
+}
+
+// addReputation lança o evento no histórico e ajusta users.reputation dentro da transação.
+// Ganhos com votos positivos respeitam o limite diário.
+func addReputation(tx *sqlx.Tx, change reputationChange) error {
+	if change.Reason == "upvote" {
+		// Travar o usuário para que votos simultâneos não furem o limite
+		if _, err := tx.Exec("SELECT id FROM users WHERE id = $1 FOR UPDATE", change.UserID); err != nil {
+			return err
+		}
+
+		var earned int
+		err := tx.Get(&earned, `
+			SELECT COALESCE(SUM(delta), 0) FROM reputation_events
+			WHERE user_id = $1 AND reason = 'upvote' AND created_at::date = $2::date
+		`, change.UserID, time.Now())
+		if err != nil {
+			return err
+		}
+		change.Delta = min(change.Delta, max(dailyReputationCap-earned, 0))
+	}
+
+	if change.Delta == 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO reputation_events (user_id, delta, reason, post_type, post_id, actor_id, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, 0), NULLIF($6, 0), $7)
+	`, change.UserID, change.Delta, change.Reason, change.PostType, change.PostID, change.ActorID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE users SET reputation = reputation + $1 WHERE id = $2", change.Delta, change.UserID)
+	return err
+}
+
+// applyVoteReputation lança os eventos de um voto novo
+func applyVoteReputation(tx *sqlx.Tx, postType string, postID, ownerID, voterID uint64, voteType int8) error {
+	if voteType == 1 {
+		delta := questionUpvoteReputation
+		if postType == "answer" {
+			delta = answerUpvoteReputation
+		}
+		return addReputation(tx, reputationChange{
+			UserID: ownerID, Delta: delta, Reason: "upvote", PostType: postType, PostID: postID, ActorID: voterID,
+		})
+	}
+
+	err := addReputation(tx, reputationChange{
+		UserID: ownerID, Delta: downvoteReceivedPenalty, Reason: "downvote", PostType: postType, PostID: postID, ActorID: voterID,
+	})
+	if err != nil || postType != "answer" {
+		return err
+	}
+
+	// Votar negativamente em respostas custa um ponto a quem vota
+	return addReputation(tx, reputationChange{
+		UserID: voterID, Delta: downvoteGivenPenalty, Reason: "downvote_given", PostType: postType, PostID: postID, ActorID: voterID,
+	})
+}
+
+// reverseVoteReputation desfaz o saldo dos eventos do voto do usuário no post
+func reverseVoteReputation(tx *sqlx.Tx, postType string, postID, voterID uint64) error {
+	var balances []struct {
+		UserID uint64 `db:"user_id"`
+		Total  int    `db:"total"`
+	}
+	err := tx.Select(&balances, `
+		SELECT user_id, SUM(delta) AS total
+		FROM reputation_events
+		WHERE post_type = $1 AND post_id = $2 AND actor_id = $3 AND reason = ANY($4)
+		GROUP BY user_id
+		HAVING SUM(delta) <> 0
+	`, postType, postID, voterID, pq.StringArray(voteReputationReasons))
+	if err != nil {
+		return err
+	}
+
+	for _, balance := range balances {
+		err := addReputation(tx, reputationChange{
+			UserID: balance.UserID, Delta: -balance.Total, Reason: "vote_reversal", PostType: postType, PostID: postID, ActorID: voterID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,13 +1,16 @@
 package handlers
 
 import (
+	"database/sql"
 	"fmt"
 	"msu-forum/database"
 	"msu-forum/models"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/lib/pq"
 )
 
 // Listar todas as tags
@@ -64,14 +67,21 @@ func GetQuestionsByTag(c *fiber.Ctx) error {
 
 	query := `
 		SELECT q.*, u.username, u.avatar_url,
-		       COUNT(DISTINCT a.id) as answer_count
+		       COUNT(DISTINCT a.id) as answer_count,
+		       b.amount as bounty_amount, b.expires_at as bounty_expires_at,
+		       EXISTS(
+		           SELECT 1 FROM question_pins p
+		           WHERE p.question_id = q.id AND (p.tag_id IS NULL OR p.tag_id = $1)
+		       ) as is_pinned,
+		       COALESCE(q.featured_until > NOW(), false) as is_featured
 		FROM questions q
 		JOIN question_tags qt ON q.id = qt.question_id
 		LEFT JOIN users u ON q.user_id = u.id
-		LEFT JOIN answers a ON q.id = a.question_id
-		WHERE qt.tag_id = $1
-		GROUP BY q.id, u.username, u.avatar_url
-		ORDER BY q.created_at DESC
+		LEFT JOIN answers a ON q.id = a.question_id AND a.deleted_at IS NULL
+		LEFT JOIN bounties b ON b.question_id = q.id AND b.status = 'active'
+		WHERE qt.tag_id = $1 AND q.deleted_at IS NULL AND ($4 OR q.closed_at IS NULL)
+		GROUP BY q.id, u.username, u.avatar_url, b.amount, b.expires_at
+		ORDER BY is_pinned DESC, is_featured DESC, q.created_at DESC
 		LIMIT $2 OFFSET $3
 	`
 
@@ -81,7 +91,7 @@ func GetQuestionsByTag(c *fiber.Ctx) error {
 		AvatarURL string `json:"avatar_url" db:"avatar_url"`
 	}
 
-	err = database.DB.Select(&questions, query, tagID, limit, offset)
+	err = database.DB.Select(&questions, query, tagID, limit, offset, includeClosed(c))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar perguntas"})
 	}
@@ -116,6 +126,10 @@ func CreateTag(c *fiber.Ctx) error {
 		return c.Status(409).JSON(fiber.Map{"error": "Tag já existe"})
 	}
 
+	if isTagSynonym(data.Name, 0) {
+		return c.Status(409).JSON(fiber.Map{"error": "Nome já é sinônimo de outra tag"})
+	}
+
 	// Criar tag
 	query := `INSERT INTO tags (name, description, usage_count, created_at) 
 			  VALUES ($1, $2, $3, $4) RETURNING id`
@@ -165,6 +179,11 @@ func UpdateTag(c *fiber.Ctx) error {
 		return c.Status(404).JSON(fiber.Map{"error": "Tag não encontrada"})
 	}
 
+	// Sinônimos da própria tag podem virar o nome principal
+	if isTagSynonym(data.Name, id) {
+		return c.Status(409).JSON(fiber.Map{"error": "Nome já é sinônimo de outra tag"})
+	}
+
 	// Atualizar tag
 	_, err = database.DB.Exec(
 		"UPDATE tags SET name = $1, description = $2 WHERE id = $3",
@@ -175,6 +194,9 @@ func UpdateTag(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar tag"})
 	}
 
+	// O sinônimo promovido a nome principal deixa de ser necessário
+	database.DB.Exec("DELETE FROM tag_synonyms WHERE name = $1 AND tag_id = $2", data.Name, id)
+
 	return c.JSON(fiber.Map{"message": "Tag atualizada com sucesso"})
 }
 
@@ -208,3 +230,111 @@ func DeleteTag(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{"message": "Tag deletada com sucesso"})
 }
+
+// findTag busca a tag pelo nome ou por um sinônimo aprovado, retornando a tag principal
+func findTag(name string) (models.Tag, error) {
+	var tag models.Tag
+	err := database.DB.Get(&tag, `
+		SELECT * FROM tags WHERE name = $1
+		UNION ALL
+		SELECT t.* FROM tag_synonyms s
+		JOIN tags t ON t.id = s.tag_id
+		WHERE s.name = $1 AND s.status = 'approved'
+		LIMIT 1
+	`, strings.TrimSpace(name))
+	return tag, err
+}
+
+// resolveTags converte os nomes informados nas tags principais, sem repetições.
+// Tags desconhecidas são criadas se o usuário tiver o privilégio create_tags.
+// Em caso de erro, retorna também o status HTTP.
+func resolveTags(c *fiber.Ctx, names []string) ([]models.Tag, int, error) {
+	tags := []models.Tag{}
+	seen := make(map[uint64]bool)
+
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		tag, err := findTag(name)
+		if err == sql.ErrNoRows {
+			ok, _, err := hasPrivilege(c, "create_tags")
+			if err != nil {
+				return nil, 500, fmt.Errorf("Erro ao verificar privilégios")
+			}
+			if !ok {
+				return nil, 400, fmt.Errorf("Tag não encontrada: %s", name)
+			}
+			err = database.DB.Get(&tag, `
+				INSERT INTO tags (name, description, usage_count, created_at) VALUES ($1, '', 0, $2)
+				ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+				RETURNING *
+			`, name, time.Now())
+		}
+		if err != nil {
+			fmt.Printf("Erro no banco: %v\n", err)
+			return nil, 500, fmt.Errorf("Erro ao buscar tags")
+		}
+
+		if !seen[tag.ID] {
+			seen[tag.ID] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags, 0, nil
+}
+
+// tagNames retorna os nomes das tags, na mesma ordem
+func tagNames(tags []models.Tag) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	return names
+}
+
+// setQuestionTags substitui as tags da pergunta e ajusta o usage_count das tags
+// adicionadas e removidas
+func setQuestionTags(questionID uint64, tagIDs []uint64) error {
+	// Array vazio, não nulo, para que ANY remova todas as tags
+	if tagIDs == nil {
+		tagIDs = []uint64{}
+	}
+
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		WITH removed AS (
+			DELETE FROM question_tags WHERE question_id = $1 AND NOT (tag_id = ANY($2))
+			RETURNING tag_id
+		)
+		UPDATE tags SET usage_count = GREATEST(usage_count - 1, 0)
+		WHERE id IN (SELECT tag_id FROM removed)
+	`, questionID, pq.Array(tagIDs))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		WITH added AS (
+			INSERT INTO question_tags (question_id, tag_id)
+			SELECT $1, unnest($2::int[])
+			ON CONFLICT DO NOTHING
+			RETURNING tag_id
+		)
+		UPDATE tags SET usage_count = usage_count + 1
+		WHERE id IN (SELECT tag_id FROM added)
+	`, questionID, pq.Array(tagIDs))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
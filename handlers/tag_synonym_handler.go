@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"msu-forum/database"
+	"msu-forum/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Sinônimos aprovados de uma tag
+func GetTagSynonyms(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	synonyms := []models.TagSynonym{}
+	err = database.DB.Select(&synonyms, `
+		SELECT * FROM tag_synonyms WHERE tag_id = $1 AND status = 'approved' ORDER BY name
+	`, id)
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar sinônimos"})
+	}
+
+	return c.JSON(synonyms)
+}
+
+// Sugerir um sinônimo para a tag (fica pendente até a moderação aprovar)
+func SuggestTagSynonym(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	var data struct {
+		Name string `json:"name" validate:"required,min=2,max=50"`
+	}
+
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "JSON inválido"})
+	}
+
+	data.Name = strings.TrimSpace(data.Name)
+	if err := Validate.Struct(data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos"})
+	}
+
+	var tag models.Tag
+	if err := database.DB.Get(&tag, "SELECT * FROM tags WHERE id = $1", id); err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Tag não encontrada"})
+	}
+
+	// Uma tag existente não pode virar sinônimo; nesse caso as tags são mescladas
+	var exists bool
+	database.DB.Get(&exists, "SELECT EXISTS(SELECT 1 FROM tags WHERE name = $1)", data.Name)
+	if exists {
+		return c.Status(409).JSON(fiber.Map{"error": "Já existe uma tag com esse nome; peça a um admin para mesclar as tags"})
+	}
+
+	if isTagSynonym(data.Name, 0) {
+		return c.Status(409).JSON(fiber.Map{"error": "Nome já é sinônimo de uma tag"})
+	}
+
+	var synonym models.TagSynonym
+	err = database.DB.Get(&synonym, `
+		INSERT INTO tag_synonyms (name, tag_id, status, suggested_by, created_at)
+		VALUES ($1, $2, 'pending', $3, $4)
+		ON CONFLICT (name, tag_id) WHERE status = 'pending' DO NOTHING
+		RETURNING *
+	`, data.Name, id, c.Locals("user_id").(int), time.Now())
+	if err == sql.ErrNoRows {
+		return c.Status(409).JSON(fiber.Map{"error": "Sinônimo já sugerido para esta tag"})
+	}
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao sugerir sinônimo"})
+	}
+
+	return c.Status(201).JSON(synonym)
+}
+
+// Fila de sugestões de sinônimos (?status=pending|approved|rejected)
+func GetTagSynonymSuggestions(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	offset := (page - 1) * limit
+
+	suggestions := []struct {
+		models.TagSynonym
+		TagName           string  `json:"tag_name" db:"tag_name"`
+		SuggestedUsername *string `json:"suggested_username" db:"suggested_username"`
+	}{}
+	err := database.DB.Select(&suggestions, `
+		SELECT s.*, t.name AS tag_name, u.username AS suggested_username
+		FROM tag_synonyms s
+		JOIN tags t ON t.id = s.tag_id
+		LEFT JOIN users u ON u.id = s.suggested_by
+		WHERE s.status = $1
+		ORDER BY s.created_at DESC
+		LIMIT $2 OFFSET $3
+	`, c.Query("status", "pending"), limit, offset)
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar sugestões"})
+	}
+
+	return c.JSON(suggestions)
+}
+
+// Aprovar ou rejeitar uma sugestão de sinônimo
+func ResolveTagSynonym(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	var data struct {
+		Status string `json:"status" validate:"required,oneof=approved rejected"`
+	}
+
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "JSON inválido"})
+	}
+
+	if err := Validate.Struct(data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos"})
+	}
+
+	var synonym models.TagSynonym
+	err = database.DB.Get(&synonym, "SELECT * FROM tag_synonyms WHERE id = $1 AND status = 'pending'", id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Sugestão não encontrada ou já avaliada"})
+	}
+
+	if data.Status == "approved" {
+		// A tag pode ter sido criada depois da sugestão
+		var exists bool
+		database.DB.Get(&exists, "SELECT EXISTS(SELECT 1 FROM tags WHERE name = $1)", synonym.Name)
+		if exists {
+			return c.Status(409).JSON(fiber.Map{"error": "Já existe uma tag com esse nome; mescle as tags"})
+		}
+
+		if isTagSynonym(synonym.Name, 0) {
+			return c.Status(409).JSON(fiber.Map{"error": "Nome já é sinônimo aprovado de outra tag"})
+		}
+	}
+
+	err = database.DB.Get(&synonym, `
+		UPDATE tag_synonyms SET status = $1, reviewed_by = $2, reviewed_at = $3
+		WHERE id = $4 AND status = 'pending'
+		RETURNING *
+	`, data.Status, c.Locals("user_id").(int), time.Now(), id)
+	if err == sql.ErrNoRows {
+		return c.Status(404).JSON(fiber.Map{"error": "Sugestão não encontrada ou já avaliada"})
+	}
+	if err != nil {
+		// Inclui a violação do índice único dos aprovados numa aprovação concorrente
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao avaliar sugestão"})
+	}
+
+	return c.JSON(synonym)
+}
+
+// Remover sinônimo ou sugestão
+func DeleteTagSynonym(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	result, err := database.DB.Exec("DELETE FROM tag_synonyms WHERE id = $1", id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao remover sinônimo"})
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Sinônimo não encontrado"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Sinônimo removido com sucesso"})
+}
+
+// Mesclar a tag :id em target_id (apenas admin). As perguntas, seguidores, destaques,
+// medalhas e sinônimos passam para a tag de destino, o nome antigo vira sinônimo
+// dela e a tag de origem é excluída.
+func MergeTags(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	var data struct {
+		TargetID uint64 `json:"target_id" validate:"required"`
+	}
+
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "JSON inválido"})
+	}
+
+	if err := Validate.Struct(data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos"})
+	}
+
+	if data.TargetID == id {
+		return c.Status(400).JSON(fiber.Map{"error": "Não é possível mesclar uma tag com ela mesma"})
+	}
+
+	userID := c.Locals("user_id").(int)
+	now := time.Now()
+
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao mesclar tags"})
+	}
+	defer tx.Rollback()
+
+	var source, target models.Tag
+	if err := tx.Get(&source, "SELECT * FROM tags WHERE id = $1 FOR UPDATE", id); err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Tag de origem não encontrada"})
+	}
+	if err := tx.Get(&target, "SELECT * FROM tags WHERE id = $1 FOR UPDATE", data.TargetID); err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Tag de destino não encontrada"})
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO question_tags (question_id, tag_id)
+		SELECT question_id, $2 FROM question_tags WHERE tag_id = $1
+		ON CONFLICT DO NOTHING
+	`, source.ID, target.ID)
+	if err != nil {
+		fmt.Printf("Erro ao mesclar tags: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao mesclar tags"})
+	}
+	moved, _ := result.RowsAffected()
+
+	steps := []string{
+		"DELETE FROM question_tags WHERE tag_id = $1",
+
+		// Seguidores da tag (follows não tem chave estrangeira para o alvo)
+		`INSERT INTO follows (user_id, target_type, target_id, created_at)
+		 SELECT user_id, 'tag', $2, created_at FROM follows WHERE target_type = 'tag' AND target_id = $1
+		 ON CONFLICT DO NOTHING`,
+		"DELETE FROM follows WHERE target_type = 'tag' AND target_id = $1",
+
+		// Destaques que já existem no destino são descartados junto com a tag
+		`UPDATE question_pins p SET tag_id = $2
+		 WHERE p.tag_id = $1
+		   AND NOT EXISTS(SELECT 1 FROM question_pins o WHERE o.question_id = p.question_id AND o.tag_id = $2)`,
+
+		// Medalhas de tag: quem tinha a da origem recebe a do destino, com a data original
+		`INSERT INTO user_badges (user_id, badge_id, awarded_at)
+		 SELECT ub.user_id, tb.id, ub.awarded_at
+		 FROM user_badges ub
+		 JOIN badges sb ON sb.id = ub.badge_id AND sb.tag_id = $1
+		 JOIN badges tb ON tb.slug = sb.slug AND tb.tag_id = $2
+		 ON CONFLICT DO NOTHING`,
+		`UPDATE badges b SET tag_id = $2, name = (SELECT name FROM tags WHERE id = $2)
+		 WHERE b.tag_id = $1
+		   AND NOT EXISTS(SELECT 1 FROM badges o WHERE o.slug = b.slug AND o.tag_id = $2)`,
+
+		`UPDATE tag_synonyms s SET tag_id = $2
+		 WHERE s.tag_id = $1
+		   AND NOT EXISTS(SELECT 1 FROM tag_synonyms o WHERE o.tag_id = $2 AND o.name = s.name AND o.status = s.status)`,
+
+		"UPDATE tags SET usage_count = (SELECT COUNT(*) FROM question_tags WHERE tag_id = $2) WHERE id = $2",
+	}
+	for _, step := range steps {
+		if _, err := tx.Exec(step, source.ID, target.ID); err != nil {
+			fmt.Printf("Erro ao mesclar tags: %v\n", err)
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao mesclar tags"})
+		}
+	}
+
+	// Webhooks filtram por nome
+	_, err = tx.Exec(`
+		UPDATE webhooks SET tags = array_replace(tags, $1, $2) WHERE $1 = ANY(tags)
+	`, source.Name, target.Name)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao mesclar tags"})
+	}
+
+	// Exclui a origem antes de registrar o nome antigo como sinônimo
+	if _, err := tx.Exec("DELETE FROM tags WHERE id = $1", source.ID); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao mesclar tags"})
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO tag_synonyms (name, tag_id, status, suggested_by, reviewed_by, reviewed_at, created_at)
+		VALUES ($1, $2, 'approved', $3, $3, $4, $4)
+		ON CONFLICT (name) WHERE status = 'approved' DO NOTHING
+	`, source.Name, target.ID, userID, now)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao mesclar tags"})
+	}
+
+	if err := tx.Get(&target, "SELECT * FROM tags WHERE id = $1", target.ID); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao mesclar tags"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao mesclar tags"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Tags mescladas com sucesso", "tag": target, "moved_questions": moved})
+}
+
+// isTagSynonym indica se o nome é sinônimo aprovado de alguma tag além de exceptTagID
+func isTagSynonym(name string, exceptTagID uint64) bool {
+	var exists bool
+	database.DB.Get(&exists, `
+		SELECT EXISTS(SELECT 1 FROM tag_synonyms WHERE name = $1 AND status = 'approved' AND tag_id <> $2)
+	`, strings.TrimSpace(name), exceptTagID)
+	return exists
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// signUserToken gera um token assinado (HMAC-SHA256) que identifica o usuário
+// para uma finalidade específica, como links de descadastro em e-mails. O token
+// deixa de valer após ttl.
+func signUserToken(purpose string, userID uint64, ttl time.Duration) string {
+	id := strconv.FormatUint(userID, 10)
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	return id + "." + expires + "." + base64.RawURLEncoding.EncodeToString(userTokenMAC(purpose, id, expires))
+}
+
+// verifyUserToken valida o token e a validade e devolve o ID do usuário
+func verifyUserToken(purpose, token string) (uint64, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("token malformado")
+	}
+	id, expires, sig := parts[0], parts[1], parts[2]
+
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, userTokenMAC(purpose, id, expires)) {
+		return 0, fmt.Errorf("assinatura inválida")
+	}
+
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return 0, fmt.Errorf("token expirado")
+	}
+
+	return strconv.ParseUint(id, 10, 64)
+}
+
+func userTokenMAC(purpose, id, expires string) []byte {
+	mac := hmac.New(sha256.New, []byte(os.Getenv(jwtSecretEnvKey)))
+	mac.Write([]byte(purpose + ":" + id + ":" + expires))
+	return mac.Sum(nil)
+}
@@ -83,8 +83,8 @@ func GetUserQuestions(c *fiber.Ctx) error {
 		       COUNT(DISTINCT a.id) as answer_count
 		FROM questions q
 		LEFT JOIN users u ON q.user_id = u.id
-		LEFT JOIN answers a ON q.id = a.question_id
-		WHERE q.user_id = $1
+		LEFT JOIN answers a ON q.id = a.question_id AND a.deleted_at IS NULL
+		WHERE q.user_id = $1 AND q.deleted_at IS NULL
 		GROUP BY q.id, u.username, u.avatar_url
 		ORDER BY q.created_at DESC
 		LIMIT $2 OFFSET $3
@@ -119,8 +119,8 @@ func GetUserAnswers(c *fiber.Ctx) error {
 		SELECT a.*, u.username, u.avatar_url, q.title as question_title
 		FROM answers a
 		LEFT JOIN users u ON a.user_id = u.id
-		LEFT JOIN questions q ON a.question_id = q.id
-		WHERE a.user_id = $1
+		JOIN questions q ON a.question_id = q.id AND q.deleted_at IS NULL
+		WHERE a.user_id = $1 AND a.deleted_at IS NULL
 		ORDER BY a.created_at DESC
 		LIMIT $2 OFFSET $3
 	`
@@ -196,6 +196,12 @@ func UpdateUserStatus(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Role inválido"})
 	}
 
+	// Estado anterior, para detectar banimento
+	var wasActive bool
+	if err := database.DB.Get(&wasActive, "SELECT is_active FROM users WHERE id = $1", userID); err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Usuário não encontrado"})
+	}
+
 	// Atualizar status
 	_, err = database.DB.Exec(
 		"UPDATE users SET is_active = $1, role = $2 WHERE id = $3",
@@ -206,5 +212,11 @@ func UpdateUserStatus(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar status do usuário"})
 	}
 
+	if wasActive && !data.IsActive {
+		dispatchWebhook("user.banned", nil, fiber.Map{
+			"user_id": userID, "banned_by": c.Locals("user_id").(int),
+		})
+	}
+
 	return c.JSON(fiber.Map{"message": "Status do usuário atualizado com sucesso"})
 }
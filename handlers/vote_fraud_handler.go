@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"msu-forum/database"
+	"msu-forum/models"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jmoiron/sqlx"
+)
+
+// Votação em série: muitos votos de um usuário nos posts de um mesmo autor
+const (
+	serialVoteWindow    = 24 * time.Hour
+	serialVoteThreshold = 5
+)
+
+// Anel de votos: dois usuários votando positivamente um no outro repetidas vezes
+const (
+	voteRingWindow    = 7 * 24 * time.Hour
+	voteRingThreshold = 4
+)
+
+// Votos de um usuário nos posts de outro, bloqueados para a reversão. Votos já
+// restaurados por um relatório descartado entre os dois usuários ficam de fora.
+const votesBetweenUsers = `
+	SELECT v.*
+	FROM votes v
+	LEFT JOIN questions q ON v.post_type = 'question' AND q.id = v.post_id
+	LEFT JOIN answers a ON v.post_type = 'answer' AND a.id = v.post_id
+	WHERE v.user_id = $1 AND COALESCE(q.user_id, a.user_id) = $2 AND v.created_at >= $3
+	  AND ($4 OR v.type = 1)
+	  AND NOT EXISTS (
+	      SELECT 1 FROM vote_fraud_reports r
+	      WHERE r.status = 'dismissed' AND v.created_at <= r.reviewed_at
+	        AND ((r.voter_id = $1 AND r.target_id = $2) OR (r.voter_id = $2 AND r.target_id = $1))
+	  )
+	ORDER BY v.created_at
+	FOR UPDATE OF v
+`
+
+// Votos com o autor do post, sem os já restaurados por um relatório descartado
+// entre os dois usuários, para que não voltem a contar na detecção
+const suspectVotes = votesWithOwner + `,
+	suspect AS (
+		SELECT o.* FROM owned o
+		WHERE NOT EXISTS (
+			SELECT 1 FROM vote_fraud_reports r
+			WHERE r.status = 'dismissed' AND o.created_at <= r.reviewed_at
+			  AND ((r.voter_id = o.voter_id AND r.target_id = o.owner_id)
+			    OR (r.voter_id = o.owner_id AND r.target_id = o.voter_id))
+		)
+	)`
+
+// Fila de votos suspeitos (?status=open|confirmed|dismissed)
+func GetVoteFraudReports(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	offset := (page - 1) * limit
+
+	reports := []struct {
+		models.VoteFraudReport
+		VoterUsername  *string `json:"voter_username" db:"voter_username"`
+		TargetUsername *string `json:"target_username" db:"target_username"`
+	}{}
+	err := database.DB.Select(&reports, `
+		SELECT r.*, voter.username AS voter_username, target.username AS target_username
+		FROM vote_fraud_reports r
+		LEFT JOIN users voter ON voter.id = r.voter_id
+		LEFT JOIN users target ON target.id = r.target_id
+		WHERE r.status = $1
+		ORDER BY r.created_at DESC
+		LIMIT $2 OFFSET $3
+	`, c.Query("status", "open"), limit, offset)
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar relatórios"})
+	}
+
+	return c.JSON(reports)
+}
+
+// Encerrar um relatório da fila (confirmed ou dismissed); ao descartar, os votos
+// revertidos voltam a valer, com a pontuação e a reputação
+func ResolveVoteFraudReport(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	var data struct {
+		Status string `json:"status" validate:"required,oneof=confirmed dismissed"`
+	}
+
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "JSON inválido"})
+	}
+
+	if err := Validate.Struct(data); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos"})
+	}
+
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao encerrar relatório"})
+	}
+	defer tx.Rollback()
+
+	var report models.VoteFraudReport
+	err = tx.Get(&report, `
+		UPDATE vote_fraud_reports SET status = $1, reviewed_by = $2, reviewed_at = $3
+		WHERE id = $4 AND status = 'open'
+		RETURNING *
+	`, data.Status, c.Locals("user_id").(int), time.Now(), id)
+	if err == sql.ErrNoRows {
+		return c.Status(404).JSON(fiber.Map{"error": "Relatório não encontrado ou já encerrado"})
+	}
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao encerrar relatório"})
+	}
+
+	var restored []models.Vote
+	if data.Status == "dismissed" {
+		var votes []models.Vote
+		if err := json.Unmarshal(report.Evidence, &votes); err != nil {
+			fmt.Printf("Evidência inválida no relatório %d: %v\n", report.ID, err)
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao restaurar votos"})
+		}
+
+		for _, vote := range votes {
+			ok, err := restoreVote(tx, vote)
+			if err != nil {
+				fmt.Printf("Erro no banco: %v\n", err)
+				return c.Status(500).JSON(fiber.Map{"error": "Erro ao restaurar votos"})
+			}
+			if ok {
+				restored = append(restored, vote)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao encerrar relatório"})
+	}
+
+	for _, vote := range restored {
+		publishVoteScore(vote.PostType, vote.PostID)
+	}
+
+	return c.JSON(fiber.Map{"report": report, "restored_votes": len(restored)})
+}
+
+// DetectVoteFraud procura votação em série e anéis de votos, reverte os votos
+// envolvidos (com a reputação) e registra as evidências na fila de moderação.
+// Executado pelo agendador.
+func DetectVoteFraud() error {
+	now := time.Now()
+
+	// Votos de um usuário para outro, positivos ou negativos
+	var serial []struct {
+		VoterID  uint64 `db:"voter_id"`
+		TargetID uint64 `db:"target_id"`
+	}
+	err := database.DB.Select(&serial, suspectVotes+`
+		SELECT voter_id, owner_id AS target_id
+		FROM suspect
+		WHERE created_at >= $1
+		GROUP BY voter_id, owner_id
+		HAVING COUNT(*) >= $2
+	`, now.Add(-serialVoteWindow), serialVoteThreshold)
+	if err != nil {
+		return err
+	}
+
+	for _, pair := range serial {
+		if err := reportVoteFraud("serial", pair.VoterID, pair.TargetID, now.Add(-serialVoteWindow), true); err != nil {
+			return err
+		}
+	}
+
+	// Pares em que os dois lados passaram do limite de votos positivos
+	var rings []struct {
+		VoterID  uint64 `db:"voter_id"`
+		TargetID uint64 `db:"target_id"`
+	}
+	err = database.DB.Select(&rings, suspectVotes+`,
+		pairs AS (
+			SELECT voter_id, owner_id FROM suspect
+			WHERE type = 1 AND created_at >= $1
+			GROUP BY voter_id, owner_id
+			HAVING COUNT(*) >= $2
+		)
+		SELECT p.voter_id, p.owner_id AS target_id
+		FROM pairs p
+		JOIN pairs back ON back.voter_id = p.owner_id AND back.owner_id = p.voter_id
+		WHERE p.voter_id < p.owner_id
+	`, now.Add(-voteRingWindow), voteRingThreshold)
+	if err != nil {
+		return err
+	}
+
+	for _, pair := range rings {
+		if err := reportVoteFraud("ring", pair.VoterID, pair.TargetID, now.Add(-voteRingWindow), false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reportVoteFraud reverte os votos do caso numa transação e abre o relatório.
+// No anel, os votos dos dois lados são revertidos.
+func reportVoteFraud(kind string, voterID, targetID uint64, since time.Time, includeDownvotes bool) error {
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var votes []models.Vote
+	if err := tx.Select(&votes, votesBetweenUsers, voterID, targetID, since, includeDownvotes); err != nil {
+		return err
+	}
+
+	if kind == "ring" {
+		var back []models.Vote
+		if err := tx.Select(&back, votesBetweenUsers, targetID, voterID, since, includeDownvotes); err != nil {
+			return err
+		}
+		votes = append(votes, back...)
+	}
+
+	// Outra execução já tratou o caso
+	if len(votes) == 0 {
+		return nil
+	}
+
+	for _, vote := range votes {
+		if err := invalidateVote(tx, vote); err != nil {
+			return err
+		}
+	}
+
+	evidence, err := json.Marshal(votes)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO vote_fraud_reports (kind, voter_id, target_id, vote_count, evidence, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, 'open', $6)
+	`, kind, voterID, targetID, len(votes), evidence, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Votos revertidos (%s): %d votos entre os usuários %d e %d\n", kind, len(votes), voterID, targetID)
+	for _, vote := range votes {
+		publishVoteScore(vote.PostType, vote.PostID)
+	}
+	return nil
+}
+
+// restoreVote recria um voto revertido por engano, com a pontuação do post e a
+// reputação. Votos em posts excluídos, ou substituídos por um voto novo do mesmo
+// usuário, não são restaurados.
+func restoreVote(tx *sqlx.Tx, vote models.Vote) (bool, error) {
+	table := "questions"
+	if vote.PostType == "answer" {
+		table = "answers"
+	}
+
+	var ownerID uint64
+	err := tx.Get(&ownerID, "SELECT user_id FROM "+table+" WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", vote.PostID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var voteID uint64
+	err = tx.Get(&voteID, `
+		INSERT INTO votes (user_id, post_id, post_type, type, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, post_id, post_type) DO NOTHING
+		RETURNING id
+	`, vote.UserID, vote.PostID, vote.PostType, vote.Type, vote.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec("UPDATE "+table+" SET votes = votes + $1 WHERE id = $2", vote.Type, vote.PostID); err != nil {
+		return false, err
+	}
+
+	if err := applyVoteReputation(tx, vote.PostType, vote.PostID, ownerID, vote.UserID, vote.Type); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// invalidateVote remove o voto, ajusta a pontuação do post e desfaz a reputação gerada
+func invalidateVote(tx *sqlx.Tx, vote models.Vote) error {
+	if err := reverseVoteReputation(tx, vote.PostType, vote.PostID, vote.UserID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM votes WHERE id = $1", vote.ID); err != nil {
+		return err
+	}
+
+	table := "questions"
+	if vote.PostType == "answer" {
+		table = "answers"
+	}
+	_, err := tx.Exec("UPDATE "+table+" SET votes = votes - $1 WHERE id = $2", vote.Type, vote.PostID)
+	return err
+}
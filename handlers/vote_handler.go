@@ -1,15 +1,29 @@
 package handlers
 
 import (
+	"database/sql"
+	"fmt"
 	"msu-forum/database"
 	"msu-forum/models"
+	"msu-forum/realtime"
 	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/jmoiron/sqlx"
 )
 
-// Votar em uma pergunta ou resposta
+// Depois desse prazo o voto só pode ser alterado se o post for editado
+const voteLockWindow = 5 * time.Minute
+
+// voteTarget é o post votado, bloqueado na transação do voto
+type voteTarget struct {
+	OwnerID  uint64     `db:"user_id"`
+	EditedAt *time.Time `db:"edited_at"`
+	Locked   bool       `db:"locked"`
+}
+
+// Votar em uma pergunta ou resposta (repetir o mesmo voto o remove)
 func Vote(c *fiber.Ctx) error {
 	var data struct {
 		PostType string `json:"post_type"` // "question" ou "answer"
@@ -33,61 +47,188 @@ func Vote(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(int)
 	now := time.Now()
 
-	// Verificar se já existe um voto do usuário
-	var existingVote models.Vote
-	err := database.DB.Get(&existingVote,
-		"SELECT * FROM votes WHERE user_id = $1 AND post_id = $2 AND post_type = $3",
+	tx, err := database.DB.Beginx()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao registrar voto"})
+	}
+	defer tx.Rollback()
+
+	// O bloqueio da linha do post serializa votos simultâneos no mesmo post
+	target, err := lockVoteTarget(tx, data.PostType, data.PostID)
+	if err == sql.ErrNoRows {
+		return c.Status(404).JSON(fiber.Map{"error": "Post não encontrado"})
+	}
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao registrar voto"})
+	}
+
+	if target.OwnerID == uint64(userID) {
+		return c.Status(403).JSON(fiber.Map{"error": "Não é possível votar no próprio post"})
+	}
+
+	if target.Locked {
+		return c.Status(403).JSON(fiber.Map{"error": "Pergunta trancada não aceita votos"})
+	}
+
+	var existing models.Vote
+	err = tx.Get(&existing,
+		"SELECT * FROM votes WHERE user_id = $1 AND post_id = $2 AND post_type = $3 FOR UPDATE",
 		userID, data.PostID, data.PostType)
+	if err != nil && err != sql.ErrNoRows {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao registrar voto"})
+	}
+	hasVote := err == nil
 
-	if err == nil {
-		// Voto já existe, atualizar
-		if existingVote.Type == data.Type {
-			// Mesmo tipo de voto, remover o voto
-			database.DB.Exec("DELETE FROM votes WHERE id = $1", existingVote.ID)
-
-			// Atualizar contador de votos
-			if data.PostType == "question" {
-				database.DB.Exec("UPDATE questions SET votes = votes - $1 WHERE id = $2", existingVote.Type, data.PostID)
-			} else {
-				database.DB.Exec("UPDATE answers SET votes = votes - $1 WHERE id = $2", existingVote.Type, data.PostID)
-			}
+	if hasVote && now.Sub(existing.CreatedAt) > voteLockWindow &&
+		(target.EditedAt == nil || !target.EditedAt.After(existing.CreatedAt)) {
+		return c.Status(403).JSON(fiber.Map{"error": "Voto bloqueado: só pode ser alterado se o post for editado"})
+	}
+
+	var delta int8
+	var state int8
+	var message string
+	status := 200
+
+	// Remover o próprio voto não exige privilégio
+	if !hasVote || existing.Type != data.Type {
+		privilege := "upvote"
+		if data.Type == -1 {
+			privilege = "downvote"
+		}
+		ok, required, err := hasPrivilege(c, privilege)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao verificar privilégios"})
+		}
+		if !ok {
+			return privilegeDenied(c, privilege, required)
+		}
+	}
+
+	// Alterar ou remover o voto desfaz a reputação que ele gerou
+	if hasVote {
+		if err := reverseVoteReputation(tx, data.PostType, data.PostID, uint64(userID)); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao registrar voto"})
+		}
+	}
 
-			return c.JSON(fiber.Map{"message": "Voto removido"})
-		} else {
-			// Tipo diferente, atualizar voto
-			database.DB.Exec("UPDATE votes SET type = $1, created_at = $2 WHERE id = $3",
-				data.Type, now, existingVote.ID)
-
-			// Atualizar contador de votos (diferença de 2)
-			voteDiff := data.Type - existingVote.Type
-			if data.PostType == "question" {
-				database.DB.Exec("UPDATE questions SET votes = votes + $1 WHERE id = $2", voteDiff, data.PostID)
-			} else {
-				database.DB.Exec("UPDATE answers SET votes = votes + $1 WHERE id = $2", voteDiff, data.PostID)
+	switch {
+	case hasVote && existing.Type == data.Type:
+		// Mesmo tipo de voto, remover o voto
+		_, err = tx.Exec("DELETE FROM votes WHERE id = $1", existing.ID)
+		delta, state, message = -existing.Type, 0, "Voto removido"
+	case hasVote:
+		// Tipo diferente, atualizar voto (diferença de 2)
+		_, err = tx.Exec("UPDATE votes SET type = $1, created_at = $2 WHERE id = $3", data.Type, now, existing.ID)
+		delta, state, message = data.Type-existing.Type, data.Type, "Voto atualizado"
+	default:
+		var result sql.Result
+		result, err = tx.Exec(`
+			INSERT INTO votes (user_id, post_id, post_type, type, created_at) VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (user_id, post_id, post_type) DO NOTHING
+		`, userID, data.PostID, data.PostType, data.Type, now)
+		if err == nil {
+			if rows, _ := result.RowsAffected(); rows == 0 {
+				return c.Status(409).JSON(fiber.Map{"error": "Voto já registrado"})
 			}
+		}
+		delta, state, message, status = data.Type, data.Type, "Voto registrado com sucesso", 201
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao registrar voto"})
+	}
 
-			return c.JSON(fiber.Map{"message": "Voto atualizado"})
+	if state != 0 {
+		if err := applyVoteReputation(tx, data.PostType, data.PostID, target.OwnerID, uint64(userID), state); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Erro ao registrar voto"})
 		}
 	}
 
-	// Criar novo voto
-	_, err = database.DB.Exec(
-		"INSERT INTO votes (user_id, post_id, post_type, type, created_at) VALUES ($1, $2, $3, $4, $5)",
-		userID, data.PostID, data.PostType, data.Type, now,
-	)
+	table := "questions"
+	if data.PostType == "answer" {
+		table = "answers"
+	}
 
+	var score int32
+	err = tx.Get(&score, "UPDATE "+table+" SET votes = votes + $1 WHERE id = $2 RETURNING votes", delta, data.PostID)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar voto"})
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao registrar voto"})
 	}
 
-	// Atualizar contador de votos
-	if data.PostType == "question" {
-		database.DB.Exec("UPDATE questions SET votes = votes + $1 WHERE id = $2", data.Type, data.PostID)
+	if err := tx.Commit(); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao registrar voto"})
+	}
+
+	publishVoteScore(data.PostType, data.PostID)
+	queueBadgeCheck(target.OwnerID, uint64(userID))
+	if state == 1 {
+		notifyUpvote(data.PostType, data.PostID, userID)
+	}
+
+	return c.Status(status).JSON(fiber.Map{"message": message, "votes": score, "vote": state})
+}
+
+// lockVoteTarget busca o post (não excluído) e bloqueia a linha até o fim da transação.
+// Retorna sql.ErrNoRows se o post não existir ou tiver sido excluído.
+func lockVoteTarget(tx *sqlx.Tx, postType string, postID uint64) (voteTarget, error) {
+	var target voteTarget
+	var err error
+
+	if postType == "question" {
+		err = tx.Get(&target, `
+			SELECT user_id, edited_at, locked_at IS NOT NULL AS locked
+			FROM questions WHERE id = $1 AND deleted_at IS NULL
+			FOR UPDATE
+		`, postID)
 	} else {
-		database.DB.Exec("UPDATE answers SET votes = votes + $1 WHERE id = $2", data.Type, data.PostID)
+		err = tx.Get(&target, `
+			SELECT a.user_id, a.edited_at, q.locked_at IS NOT NULL AS locked
+			FROM answers a
+			JOIN questions q ON q.id = a.question_id AND q.deleted_at IS NULL
+			WHERE a.id = $1 AND a.deleted_at IS NULL
+			FOR UPDATE OF a
+		`, postID)
+	}
+
+	return target, err
+}
+
+// publishVoteScore envia a pontuação atualizada do post para quem acompanha a pergunta
+func publishVoteScore(postType string, postID uint64) {
+	var votes int32
+	var questionID uint64
+	var err error
+	if postType == "question" {
+		questionID = postID
+		err = database.DB.Get(&votes, "SELECT votes FROM questions WHERE id = $1", postID)
+	} else {
+		var answer models.Answer
+		err = database.DB.Get(&answer, "SELECT question_id, votes FROM answers WHERE id = $1", postID)
+		questionID, votes = answer.QuestionID, answer.Votes
+	}
+	if err != nil {
+		return
+	}
+
+	realtime.Publish(realtime.QuestionTopic(questionID), "vote.updated", fiber.Map{
+		"post_type": postType, "post_id": postID, "votes": votes,
+	})
+}
+
+// notifyUpvote avisa o autor do post sobre o voto positivo
+func notifyUpvote(postType string, postID uint64, voterID int) {
+	ownerID, err := postOwner(postType, postID)
+	if err != nil {
+		return
 	}
 
-	return c.Status(201).JSON(fiber.Map{"message": "Voto registrado com sucesso"})
+	notify(notificationEvent{
+		UserID:   ownerID,
+		ActorID:  uint64(voterID),
+		Type:     "upvote",
+		PostType: postType,
+		PostID:   postID,
+	})
 }
 
 // Obter votos de um usuário
@@ -0,0 +1,406 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"msu-forum/database"
+	"msu-forum/models"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/lib/pq"
+)
+
+const (
+	webhookTimeout      = 10 * time.Second
+	webhookMaxAttempts  = 8
+	webhookBaseBackoff  = 30 * time.Second
+	webhookMaxBackoff   = 6 * time.Hour
+	webhookBatchSize    = 50
+	webhookErrorMaxSize = 500
+
+	// Tempo reservado para enviar um lote; se a instância cair no meio, as entregas
+	// voltam para a fila depois desse prazo
+	webhookClaimTimeout = webhookBatchSize * webhookTimeout
+)
+
+// Eventos que podem ser assinados
+var webhookEvents = map[string]bool{
+	"question.created": true,
+	"answer.created":   true,
+	"answer.accepted":  true,
+	"user.banned":      true,
+}
+
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+type webhookInput struct {
+	URL      string   `json:"url" validate:"required,url,max=500"`
+	Format   string   `json:"format" validate:"omitempty,oneof=json discord"`
+	Events   []string `json:"events" validate:"required,min=1"`
+	Tags     []string `json:"tags"`
+	IsActive *bool    `json:"is_active"`
+}
+
+// Listar webhooks (apenas admin)
+func GetWebhooks(c *fiber.Ctx) error {
+	webhooks := []models.Webhook{}
+	if err := database.DB.Select(&webhooks, "SELECT * FROM webhooks ORDER BY created_at DESC"); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar webhooks"})
+	}
+	return c.JSON(webhooks)
+}
+
+// Criar webhook (apenas admin). O segredo para validar a assinatura é gerado pelo servidor.
+func CreateWebhook(c *fiber.Ctx) error {
+	data, err := parseWebhookInput(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar webhook"})
+	}
+
+	isActive := data.IsActive == nil || *data.IsActive
+	userID := c.Locals("user_id").(int)
+
+	var webhook models.Webhook
+	err = database.DB.Get(&webhook, `
+		INSERT INTO webhooks (url, secret, format, events, tags, is_active, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING *
+	`, data.URL, hex.EncodeToString(secret), data.Format, pq.StringArray(data.Events), pq.StringArray(data.Tags), isActive, userID, time.Now())
+	if err != nil {
+		fmt.Printf("Erro no banco: %v\n", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar webhook"})
+	}
+
+	return c.Status(201).JSON(webhook)
+}
+
+// Atualizar webhook (apenas admin)
+func UpdateWebhook(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	data, err := parseWebhookInput(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	isActive := data.IsActive == nil || *data.IsActive
+
+	var webhook models.Webhook
+	err = database.DB.Get(&webhook, `
+		UPDATE webhooks SET url = $1, format = $2, events = $3, tags = $4, is_active = $5
+		WHERE id = $6
+		RETURNING *
+	`, data.URL, data.Format, pq.StringArray(data.Events), pq.StringArray(data.Tags), isActive, id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Webhook não encontrado"})
+	}
+
+	return c.JSON(webhook)
+}
+
+// Deletar webhook (apenas admin)
+func DeleteWebhook(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	result, err := database.DB.Exec("DELETE FROM webhooks WHERE id = $1", id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar webhook"})
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Webhook não encontrado"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Webhook deletado com sucesso"})
+}
+
+// Log de entregas de um webhook (apenas admin)
+func GetWebhookDeliveries(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	offset := (page - 1) * limit
+
+	deliveries := []models.WebhookDelivery{}
+	err = database.DB.Select(&deliveries, `
+		SELECT * FROM webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`, id, limit, offset)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar entregas"})
+	}
+
+	return c.JSON(deliveries)
+}
+
+// Reenviar uma entrega (cria uma nova entrega com o mesmo payload)
+func RedeliverWebhook(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "ID inválido"})
+	}
+
+	var delivery models.WebhookDelivery
+	err = database.DB.Get(&delivery, `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload, status, attempts, next_attempt_at, created_at)
+		SELECT webhook_id, event, payload, 'pending', 0, $2, $2
+		FROM webhook_deliveries WHERE id = $1
+		RETURNING *
+	`, id, time.Now())
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Entrega não encontrada"})
+	}
+
+	return c.Status(201).JSON(delivery)
+}
+
+// dispatchWebhook enfileira o evento para os webhooks ativos que o assinam.
+// tags filtra webhooks restritos a tags (nil para eventos sem tags).
+func dispatchWebhook(event string, tags []string, data interface{}) {
+	payload, err := json.Marshal(fiber.Map{
+		"event":      event,
+		"created_at": time.Now(),
+		"data":       data,
+	})
+	if err != nil {
+		fmt.Printf("Erro ao serializar webhook %s: %v\n", event, err)
+		return
+	}
+
+	if tags == nil {
+		tags = []string{}
+	}
+
+	// Webhooks sem filtro de tag recebem tudo; os demais só se houver interseção
+	_, err = database.DB.Exec(`
+		INSERT INTO webhook_deliveries (webhook_id, event, payload, status, attempts, next_attempt_at, created_at)
+		SELECT id, $1, $2, 'pending', 0, $4, $4
+		FROM webhooks
+		WHERE is_active = true AND $1 = ANY(events)
+		  AND (cardinality(tags) = 0 OR tags && $3)
+	`, event, payload, pq.StringArray(tags), time.Now())
+	if err != nil {
+		fmt.Printf("Erro ao enfileirar webhook %s: %v\n", event, err)
+	}
+}
+
+// ProcessWebhookDeliveries envia as entregas pendentes, com backoff exponencial
+// entre tentativas. Executado pelo agendador.
+func ProcessWebhookDeliveries() error {
+	// Reserva o lote adiando next_attempt_at; o UPDATE roda na própria transação e
+	// libera os registros antes dos envios. SKIP LOCKED permite várias instâncias
+	// processarem a fila sem duplicar envios.
+	var deliveries []struct {
+		models.WebhookDelivery
+		URL    string `db:"url"`
+		Secret string `db:"secret"`
+		Format string `db:"format"`
+	}
+	now := time.Now()
+	err := database.DB.Select(&deliveries, `
+		WITH claimed AS (
+			UPDATE webhook_deliveries
+			SET next_attempt_at = $3
+			WHERE id IN (
+				SELECT id FROM webhook_deliveries
+				WHERE status = 'pending' AND next_attempt_at <= $1
+				ORDER BY next_attempt_at
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING *
+		)
+		SELECT d.*, w.url, w.secret, w.format
+		FROM claimed d
+		JOIN webhooks w ON w.id = d.webhook_id
+		ORDER BY d.id
+	`, now, webhookBatchSize, now.Add(webhookClaimTimeout))
+	if err != nil {
+		return err
+	}
+
+	for _, d := range deliveries {
+		statusCode, sendErr := sendWebhook(d.URL, d.Secret, d.Format, d.WebhookDelivery)
+		attempts := d.Attempts + 1
+		now := time.Now()
+
+		var code *int32
+		if statusCode > 0 {
+			c := int32(statusCode)
+			code = &c
+		}
+
+		if sendErr == nil {
+			_, err = database.DB.Exec(`
+				UPDATE webhook_deliveries
+				SET status = 'success', attempts = $1, last_status_code = $2, last_error = NULL, delivered_at = $3
+				WHERE id = $4
+			`, attempts, code, now, d.ID)
+		} else {
+			status := "pending"
+			if attempts >= webhookMaxAttempts {
+				status = "failed"
+			}
+
+			message := sendErr.Error()
+			if len(message) > webhookErrorMaxSize {
+				message = message[:webhookErrorMaxSize]
+			}
+
+			_, err = database.DB.Exec(`
+				UPDATE webhook_deliveries
+				SET status = $1, attempts = $2, last_status_code = $3, last_error = $4, next_attempt_at = $5
+				WHERE id = $6
+			`, status, attempts, code, message, now.Add(webhookBackoff(attempts)), d.ID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sendWebhook faz o POST assinado; retorna o status HTTP (0 se não houve resposta)
+func sendWebhook(target, secret, format string, delivery models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	if format == "discord" {
+		var err error
+		if body, err = discordPayload(delivery); err != nil {
+			return 0, err
+		}
+	}
+
+	req, err := http.NewRequest("POST", target, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "MSU-Forum-Webhooks/1.0")
+	req.Header.Set("X-Forum-Event", delivery.Event)
+	req.Header.Set("X-Forum-Delivery", strconv.FormatUint(delivery.ID, 10))
+	req.Header.Set("X-Forum-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("resposta HTTP %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// discordPayload converte o evento no formato aceito pelos webhooks do Discord
+func discordPayload(delivery models.WebhookDelivery) ([]byte, error) {
+	var envelope struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(delivery.Payload, &envelope); err != nil {
+		return nil, err
+	}
+
+	content := fmt.Sprintf("**%s**", delivery.Event)
+	if title, ok := envelope.Data["title"].(string); ok {
+		content += ": " + title
+	}
+	if link, ok := envelope.Data["url"].(string); ok {
+		content += "\n" + link
+	}
+
+	return json.Marshal(fiber.Map{"content": content})
+}
+
+// webhookBackoff: 30s, 1min, 2min, 4min... limitado a 6h
+func webhookBackoff(attempts int32) time.Duration {
+	backoff := webhookBaseBackoff << (attempts - 1)
+	if backoff <= 0 || backoff > webhookMaxBackoff {
+		return webhookMaxBackoff
+	}
+	return backoff
+}
+
+func parseWebhookInput(c *fiber.Ctx) (webhookInput, error) {
+	var data webhookInput
+	if err := c.BodyParser(&data); err != nil {
+		return data, fmt.Errorf("JSON inválido")
+	}
+
+	if err := Validate.Struct(data); err != nil {
+		return data, fmt.Errorf("Dados inválidos: %v", err)
+	}
+
+	if u, err := url.Parse(data.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return data, fmt.Errorf("URL deve usar http ou https")
+	}
+
+	for _, event := range data.Events {
+		if !webhookEvents[event] {
+			return data, fmt.Errorf("Evento inválido: %s", event)
+		}
+	}
+
+	if data.Format == "" {
+		data.Format = "json"
+	}
+	if data.Tags == nil {
+		data.Tags = []string{}
+	}
+	for i, tag := range data.Tags {
+		data.Tags[i] = strings.TrimSpace(tag)
+	}
+
+	return data, nil
+}
+
+// questionURL monta o link público da pergunta para os payloads
+func questionURL(questionID uint64) string {
+	return fmt.Sprintf("%s/questions/%d", strings.TrimRight(os.Getenv("APP_URL"), "/"), questionID)
+}
+
+// questionTagNames retorna as tags da pergunta, usadas no filtro dos webhooks
+func questionTagNames(questionID uint64) []string {
+	names := []string{}
+	database.DB.Select(&names, `
+		SELECT t.name FROM tags t
+		JOIN question_tags qt ON qt.tag_id = t.id
+		WHERE qt.question_id = $1
+	`, questionID)
+	return names
+}
@@ -0,0 +1,31 @@
+package jobs
+
+import (
+	"log"
+	"time"
+)
+
+// Job é uma tarefa periódica executada em segundo plano
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func() error
+}
+
+// Start inicia cada job em sua própria goroutine
+func Start(jobs ...Job) {
+	for _, job := range jobs {
+		go run(job)
+	}
+}
+
+func run(job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := job.Run(); err != nil {
+			log.Printf("Erro no job %s: %v", job.Name, err)
+		}
+	}
+}
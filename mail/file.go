@@ -0,0 +1,41 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+var fileCounter uint64
+
+// FileTransport grava cada mensagem como arquivo .eml no diretório informado,
+// no formato de maildir (tmp/ e new/), para inspeção local sem servidor SMTP
+type FileTransport struct {
+	Dir  string
+	From string
+}
+
+func (t *FileTransport) Send(msg Message) error {
+	body, err := build(t.From, msg)
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(t.Dir, sub), 0o755); err != nil {
+			return err
+		}
+	}
+
+	hostname, _ := os.Hostname()
+	name := fmt.Sprintf("%d.%d_%d.%s.eml", time.Now().Unix(), os.Getpid(), atomic.AddUint64(&fileCounter, 1), hostname)
+
+	// Escrever em tmp/ e mover para new/ garante que leitores nunca vejam arquivo parcial
+	tmpPath := filepath.Join(t.Dir, "tmp", name)
+	if err := os.WriteFile(tmpPath, body, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filepath.Join(t.Dir, "new", name))
+}
@@ -0,0 +1,51 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+)
+
+// Message é um e-mail pronto para envio, com versões texto e HTML
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	// Cabeçalhos extras (ex.: List-Unsubscribe)
+	Headers map[string]string
+}
+
+// Transport envia mensagens; implementações: SMTP e arquivo (testes locais)
+type Transport interface {
+	Send(msg Message) error
+}
+
+// Default é o transporte usado pelos handlers; configurado em main
+var Default Transport = &FileTransport{Dir: "./maildir", From: "MSU Forum <no-reply@localhost>"}
+
+// NewTransportFromEnv escolhe o transporte conforme MAIL_TRANSPORT ("smtp" ou "file")
+func NewTransportFromEnv() (Transport, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "MSU Forum <no-reply@localhost>"
+	}
+
+	switch os.Getenv("MAIL_TRANSPORT") {
+	case "smtp":
+		return &SMTPTransport{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USER"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}, nil
+	case "file", "":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "./maildir"
+		}
+		return &FileTransport{Dir: dir, From: from}, nil
+	}
+
+	return nil, fmt.Errorf("MAIL_TRANSPORT inválido: %s", os.Getenv("MAIL_TRANSPORT"))
+}
@@ -0,0 +1,34 @@
+package mail
+
+import (
+	"net/mail"
+	"net/smtp"
+)
+
+// SMTPTransport envia pelo servidor SMTP configurado (STARTTLS quando disponível)
+type SMTPTransport struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (t *SMTPTransport) Send(msg Message) error {
+	body, err := build(t.From, msg)
+	if err != nil {
+		return err
+	}
+
+	from, err := mail.ParseAddress(t.From)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if t.Username != "" {
+		auth = smtp.PlainAuth("", t.Username, t.Password, t.Host)
+	}
+
+	return smtp.SendMail(t.Host+":"+t.Port, auth, from.Address, []string{msg.To}, body)
+}
@@ -0,0 +1,30 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+//go:embed templates/*
+var templateFS embed.FS
+
+var (
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/*.html"))
+	textTemplates = texttemplate.Must(texttemplate.ParseFS(templateFS, "templates/*.txt"))
+)
+
+// Render executa os templates <name>.txt e <name>.html com os mesmos dados
+func Render(name string, data interface{}) (text string, html string, err error) {
+	var textBuf, htmlBuf bytes.Buffer
+
+	if err := textTemplates.ExecuteTemplate(&textBuf, name+".txt", data); err != nil {
+		return "", "", err
+	}
+	if err := htmlTemplates.ExecuteTemplate(&htmlBuf, name+".html", data); err != nil {
+		return "", "", err
+	}
+
+	return textBuf.String(), htmlBuf.String(), nil
+}
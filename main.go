@@ -4,8 +4,13 @@ import (
 	"log"
 	"msu-forum/database"
 	"msu-forum/handlers"
+	"msu-forum/jobs"
+	"msu-forum/mail"
 	"msu-forum/middleware"
+	"msu-forum/realtime"
+	"msu-forum/storage"
 	"os"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/joho/godotenv"
@@ -20,7 +25,50 @@ func main() {
 
 	database.Connect()
 
-	app := fiber.New()
+	// Broker do tempo real: "postgres" compartilha eventos entre instâncias via LISTEN/NOTIFY
+	if os.Getenv("REALTIME_BROKER") == "postgres" {
+		broker, err := realtime.NewPostgresBroker(database.DSN(), func(channel, payload string) error {
+			_, err := database.DB.Exec("SELECT pg_notify($1, $2)", channel, payload)
+			return err
+		})
+		if err != nil {
+			log.Fatal("Erro ao iniciar broker do tempo real:", err)
+		}
+		realtime.Default = realtime.NewHub(broker)
+	}
+
+	transport, err := mail.NewTransportFromEnv()
+	if err != nil {
+		log.Fatal("Erro ao configurar envio de e-mails:", err)
+	}
+	mail.Default = transport
+
+	uploads, err := storage.NewFromEnv()
+	if err != nil {
+		log.Fatal("Erro ao configurar armazenamento de anexos:", err)
+	}
+	storage.Default = uploads
+
+	if err := handlers.SyncBadges(); err != nil {
+		log.Fatal("Erro ao sincronizar medalhas:", err)
+	}
+	handlers.StartBadgeEvaluator()
+
+	// Tarefas agendadas
+	jobs.Start(
+		jobs.Job{Name: "expirar-recompensas", Interval: 5 * time.Minute, Run: handlers.ExpireBounties},
+		jobs.Job{Name: "limpar-rascunhos", Interval: time.Hour, Run: handlers.PurgeStaleDrafts},
+		jobs.Job{Name: "purgar-posts-excluidos", Interval: 24 * time.Hour, Run: handlers.PurgeDeletedPosts},
+		jobs.Job{Name: "enviar-resumos", Interval: time.Hour, Run: handlers.SendDigests},
+		jobs.Job{Name: "entregar-webhooks", Interval: 15 * time.Second, Run: handlers.ProcessWebhookDeliveries},
+		jobs.Job{Name: "limpar-anexos", Interval: time.Hour, Run: handlers.PurgeOrphanAttachments},
+		jobs.Job{Name: "analisar-votos", Interval: time.Hour, Run: handlers.DetectVoteFraud},
+		jobs.Job{Name: "avaliar-medalhas", Interval: time.Hour, Run: handlers.EvaluateBadges},
+		jobs.Job{Name: "calcular-rankings", Interval: 15 * time.Minute, Run: handlers.RefreshLeaderboards},
+	)
+
+	// Limite acima do tamanho máximo de anexo, para caber o multipart
+	app := fiber.New(fiber.Config{BodyLimit: 10 * 1024 * 1024})
 
 	// Middlewares
 	app.Use(middleware.CORSMiddleware())
@@ -33,30 +81,117 @@ func main() {
 	app.Post("/wallet", handlers.HasUserWithThisWallet)
 	app.Get("/questions", handlers.GetQuestions)
 	app.Get("/questions/search", handlers.SearchQuestions)
+	app.Get("/questions/bounties", handlers.GetBountiedQuestions)
+	app.Get("/questions/:id", handlers.GetQuestion)
+	app.Get("/comments", handlers.GetComments)
+	app.Get("/reactions", handlers.GetReactionUsers)
+	app.Get("/reactions/types", handlers.GetReactionTypes)
+	app.Get("/digest/unsubscribe", handlers.ConfirmUnsubscribeDigest)
+	app.Post("/digest/unsubscribe", handlers.UnsubscribeDigest)
+	app.Get("/collections/shared/:token", handlers.GetSharedCollection)
+	app.Get("/users/:id/reputation", handlers.GetUserReputation)
+	app.Get("/users/:id/badges", handlers.GetUserBadges)
+	app.Get("/badges", handlers.GetBadges)
+	app.Get("/badges/:id/holders", handlers.GetBadgeHolders)
+	app.Get("/leaderboards", handlers.GetLeaderboard)
 	app.Get("/tags", handlers.GetTags)
 	app.Get("/tags/:id", handlers.GetTag)
+	app.Get("/tags/:id/synonyms", handlers.GetTagSynonyms)
 	app.Get("/tags/:tagId/questions", handlers.GetQuestionsByTag)
 
 	// Rotas protegidas
-	api := app.Group("/api", middleware.AuthRequired)
+	api := app.Group("/api", middleware.AuthRequired, handlers.RecordVisit)
 	v1 := api.Group("/v1")
 
 	// Perguntas
 	v1.Post("/questions", handlers.CreateQuestion)
 	v1.Put("/questions/:id", handlers.UpdateQuestion)
 	v1.Delete("/questions/:id", handlers.DeleteQuestion)
+	v1.Post("/questions/:id/undelete", handlers.UndeleteQuestion)
+	v1.Post("/questions/:id/close", handlers.CloseQuestion)
+	v1.Post("/questions/:id/reopen", handlers.ReopenQuestion)
+	v1.Get("/questions/:id/close-votes", handlers.GetCloseVotes)
+	v1.Post("/questions/:id/bounty", handlers.CreateBounty)
+	v1.Post("/questions/:id/bounty/award", handlers.AwardBounty)
+	v1.Get("/questions/:id/bounties", handlers.GetQuestionBounties)
 
 	// Respostas
 	v1.Post("/questions/:questionId/answers", handlers.CreateAnswer)
 	v1.Get("/questions/:questionId/answers", handlers.GetAnswers)
 	v1.Put("/answers/:id", handlers.UpdateAnswer)
 	v1.Delete("/answers/:id", handlers.DeleteAnswer)
+	v1.Post("/answers/:id/undelete", handlers.UndeleteAnswer)
 	v1.Post("/answers/:id/accept", handlers.AcceptAnswer)
+	v1.Delete("/answers/:id/accept", handlers.UnacceptAnswer)
+
+	// Comentários
+	v1.Post("/comments", handlers.CreateComment)
+	v1.Put("/comments/:id", handlers.UpdateComment)
+	v1.Delete("/comments/:id", handlers.DeleteComment)
+	v1.Post("/comments/:id/upvote", handlers.UpvoteComment)
 
 	// Votos
 	v1.Post("/votes", handlers.Vote)
 	v1.Get("/votes", handlers.GetUserVotes)
 
+	// Reações
+	v1.Post("/reactions", handlers.ToggleReaction)
+
+	// Seguir tags, perguntas e usuários
+	v1.Get("/follows", handlers.GetFollows)
+	v1.Post("/tags/:id/follow", handlers.FollowTag)
+	v1.Delete("/tags/:id/follow", handlers.UnfollowTag)
+	v1.Post("/tags/:id/synonyms", handlers.SuggestTagSynonym)
+	v1.Post("/questions/:id/follow", handlers.FollowQuestion)
+	v1.Delete("/questions/:id/follow", handlers.UnfollowQuestion)
+	v1.Post("/users/:id/follow", handlers.FollowUser)
+	v1.Delete("/users/:id/follow", handlers.UnfollowUser)
+	v1.Get("/feed", handlers.GetFeed)
+
+	// Favoritos
+	v1.Get("/collections", handlers.GetCollections)
+	v1.Post("/collections", handlers.CreateCollection)
+	v1.Get("/collections/:id", handlers.GetCollection)
+	v1.Put("/collections/:id", handlers.UpdateCollection)
+	v1.Delete("/collections/:id", handlers.DeleteCollection)
+	v1.Put("/collections/:id/order", handlers.ReorderCollection)
+	v1.Post("/collections/:id/bookmarks", handlers.AddBookmark)
+	v1.Put("/bookmarks/:id", handlers.UpdateBookmark)
+	v1.Delete("/bookmarks/:id", handlers.DeleteBookmark)
+
+	// Resumo por e-mail
+	v1.Get("/digest", handlers.GetDigestSettings)
+	v1.Put("/digest", handlers.UpdateDigestSettings)
+
+	// Rascunhos
+	v1.Get("/drafts", handlers.GetDrafts)
+	v1.Put("/drafts", handlers.SaveDraft)
+	v1.Get("/drafts/:targetType/:targetId", handlers.GetDraft)
+	v1.Delete("/drafts/:targetType/:targetId", handlers.DeleteDraft)
+
+	// Notificações
+	v1.Get("/notifications", handlers.GetNotifications)
+	v1.Get("/notifications/unread-count", handlers.GetUnreadNotificationCount)
+	v1.Post("/notifications/read-all", handlers.MarkAllNotificationsRead)
+	v1.Post("/notifications/:id/read", handlers.MarkNotificationRead)
+	v1.Get("/notifications/preferences", handlers.GetNotificationPreferences)
+	v1.Put("/notifications/preferences", handlers.UpdateNotificationPreferences)
+
+	// Menções
+	v1.Get("/mentions", handlers.GetMyMentions)
+
+	// Privilégios por reputação
+	v1.Get("/privileges", handlers.GetMyPrivileges)
+	v1.Get("/reputation", handlers.GetMyReputation)
+
+	// Anexos
+	v1.Post("/attachments", handlers.UploadAttachment)
+	v1.Get("/attachments/:id", handlers.GetAttachment)
+	v1.Delete("/attachments/:id", handlers.DeleteAttachment)
+
+	// Tempo real (Server-Sent Events)
+	v1.Get("/realtime", handlers.StreamEvents)
+
 	// Usuários
 	v1.Get("/profile", handlers.GetProfile)
 	v1.Put("/profile", handlers.UpdateProfile)
@@ -72,11 +207,48 @@ func main() {
 		return c.Next()
 	})
 
+	// Moderação
+	mod := v1.Group("/mod", middleware.RoleRequired("Admin", "Moderator"))
+	mod.Post("/questions/:id/pin", handlers.PinQuestion)
+	mod.Delete("/questions/:id/pin", handlers.UnpinQuestion)
+	mod.Post("/questions/:id/feature", handlers.FeatureQuestion)
+	mod.Delete("/questions/:id/feature", handlers.UnfeatureQuestion)
+	mod.Post("/questions/:id/lock", handlers.LockQuestion)
+	mod.Delete("/questions/:id/lock", handlers.UnlockQuestion)
+	mod.Get("/deleted", handlers.GetDeletedPosts)
+	mod.Get("/users/:id/reputation", handlers.GetUserReputationHistory)
+	mod.Get("/vote-fraud", handlers.GetVoteFraudReports)
+	mod.Post("/vote-fraud/:id/resolve", handlers.ResolveVoteFraudReport)
+	mod.Get("/tag-synonyms", handlers.GetTagSynonymSuggestions)
+	mod.Post("/tag-synonyms/:id/resolve", handlers.ResolveTagSynonym)
+	mod.Delete("/tag-synonyms/:id", handlers.DeleteTagSynonym)
+
 	admin.Get("/users", handlers.GetUsers)
 	admin.Put("/users/:userId/status", handlers.UpdateUserStatus)
 	admin.Post("/tags", handlers.CreateTag)
 	admin.Put("/tags/:id", handlers.UpdateTag)
 	admin.Delete("/tags/:id", handlers.DeleteTag)
+	admin.Post("/tags/:id/merge", handlers.MergeTags)
+	admin.Post("/posts/render", handlers.RerenderPosts)
+	admin.Post("/reputation/recompute", handlers.RecomputeReputation)
+	admin.Put("/privileges/:name", handlers.UpdatePrivilege)
+	admin.Get("/users/:userId/privileges", handlers.GetUserPrivileges)
+	admin.Put("/users/:userId/privileges/:name", handlers.SetPrivilegeOverride)
+	admin.Delete("/users/:userId/privileges/:name", handlers.DeletePrivilegeOverride)
+
+	// Reações
+	admin.Get("/reactions", handlers.GetAllReactionTypes)
+	admin.Post("/reactions", handlers.CreateReactionType)
+	admin.Put("/reactions/:name", handlers.UpdateReactionType)
+	admin.Delete("/reactions/:name", handlers.DeleteReactionType)
+
+	// Webhooks
+	admin.Get("/webhooks", handlers.GetWebhooks)
+	admin.Post("/webhooks", handlers.CreateWebhook)
+	admin.Put("/webhooks/:id", handlers.UpdateWebhook)
+	admin.Delete("/webhooks/:id", handlers.DeleteWebhook)
+	admin.Get("/webhooks/:id/deliveries", handlers.GetWebhookDeliveries)
+	admin.Post("/webhooks/deliveries/:id/redeliver", handlers.RedeliverWebhook)
 
 	port := os.Getenv("APP_PORT")
 	if port == "" {
@@ -0,0 +1,17 @@
+package middleware
+
+import "github.com/gofiber/fiber/v2"
+
+// RoleRequired permite a requisição apenas para os papéis informados.
+// Deve ser usado depois de AuthRequired.
+func RoleRequired(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role, _ := c.Locals("role").(string)
+		for _, allowed := range roles {
+			if role == allowed {
+				return c.Next()
+			}
+		}
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso negado"})
+	}
+}
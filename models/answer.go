@@ -3,14 +3,20 @@ package models
 import "time"
 
 type Answer struct {
-	ID         uint64    `json:"id" db:"id"`
-	QuestionID uint64    `json:"question_id" db:"question_id"`
-	UserID     uint64    `json:"user_id" db:"user_id"`
-	Body       string    `json:"body" db:"body"`
-	Votes      int32     `json:"votes" db:"votes"`
-	IsAccepted bool      `json:"is_accepted" db:"is_accepted"`
-	CreatedAt  time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	ID            uint64     `json:"id" db:"id"`
+	QuestionID    uint64     `json:"question_id" db:"question_id"`
+	UserID        uint64     `json:"user_id" db:"user_id"`
+	Body          string     `json:"body" db:"body"`
+	BodyHTML      string     `json:"body_html" db:"body_html"` // Markdown renderizado e sanitizado
+	Votes         int32      `json:"votes" db:"votes"`
+	IsAccepted    bool       `json:"is_accepted" db:"is_accepted"`
+	AcceptedAt    *time.Time `json:"accepted_at" db:"accepted_at"`
+	BookmarkCount uint32     `json:"bookmark_count" db:"bookmark_count"`
+	EditedAt      *time.Time `json:"edited_at" db:"edited_at"` // última edição do conteúdo pelo autor
+	DeletedAt     *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	DeletedBy     *uint64    `json:"deleted_by,omitempty" db:"deleted_by"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
 
 	// Otimização: cache do usuário
 	user *User
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+type Attachment struct {
+	ID           uint64    `json:"id" db:"id"`
+	UserID       *uint64   `json:"user_id" db:"user_id"`
+	Hash         string    `json:"hash" db:"hash"` // SHA-256 do arquivo enviado, usado na deduplicação
+	StorageKey   string    `json:"-" db:"storage_key"`
+	ThumbnailKey *string   `json:"-" db:"thumbnail_key"`
+	Filename     string    `json:"filename" db:"filename"`
+	MimeType     string    `json:"mime_type" db:"mime_type"`
+	Size         int64     `json:"size" db:"size"`
+	Width        *int32    `json:"width" db:"width"`
+	Height       *int32    `json:"height" db:"height"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+type Badge struct {
+	ID          uint64    `json:"id" db:"id"`
+	Slug        string    `json:"slug" db:"slug"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	Tier        string    `json:"tier" db:"tier"`     // "bronze", "silver" ou "gold"
+	TagID       *uint64   `json:"tag_id" db:"tag_id"` // medalhas de tag
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+type UserBadge struct {
+	UserID    uint64    `json:"user_id" db:"user_id"`
+	BadgeID   uint64    `json:"badge_id" db:"badge_id"`
+	AwardedAt time.Time `json:"awarded_at" db:"awarded_at"`
+}
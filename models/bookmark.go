@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+type BookmarkCollection struct {
+	ID          uint64    `json:"id" db:"id"`
+	UserID      uint64    `json:"user_id" db:"user_id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	IsPublic    bool      `json:"is_public" db:"is_public"`
+	ShareToken  string    `json:"share_token" db:"share_token"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type Bookmark struct {
+	ID           uint64    `json:"id" db:"id"`
+	CollectionID uint64    `json:"collection_id" db:"collection_id"`
+	UserID       uint64    `json:"user_id" db:"user_id"`
+	PostType     string    `json:"post_type" db:"post_type"` // "question" ou "answer"
+	PostID       uint64    `json:"post_id" db:"post_id"`
+	Note         string    `json:"note" db:"note"`
+	Position     int32     `json:"position" db:"position"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+type Bounty struct {
+	ID              uint64     `json:"id" db:"id"`
+	QuestionID      uint64     `json:"question_id" db:"question_id"`
+	UserID          uint64     `json:"user_id" db:"user_id"`
+	Amount          int32      `json:"amount" db:"amount"`
+	Status          string     `json:"status" db:"status"` // "active", "awarded" ou "expired"
+	AwardedAnswerID *uint64    `json:"awarded_answer_id" db:"awarded_answer_id"`
+	ExpiresAt       time.Time  `json:"expires_at" db:"expires_at"`
+	AwardedAt       *time.Time `json:"awarded_at" db:"awarded_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type CloseVote struct {
+	QuestionID  uint64    `json:"question_id" db:"question_id"`
+	UserID      uint64    `json:"user_id" db:"user_id"`
+	VoteType    string    `json:"vote_type" db:"vote_type"` // "close" ou "reopen"
+	Reason      *string   `json:"reason" db:"reason"`
+	DuplicateOf *uint64   `json:"duplicate_of" db:"duplicate_of"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
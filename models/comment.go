@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+type Comment struct {
+	ID        uint64     `json:"id" db:"id"`
+	PostType  string     `json:"post_type" db:"post_type"` // "question" ou "answer"
+	PostID    uint64     `json:"post_id" db:"post_id"`
+	UserID    uint64     `json:"user_id" db:"user_id"`
+	Body      string     `json:"body" db:"body"`
+	Votes     int32      `json:"votes" db:"votes"` // apenas votos positivos
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+}
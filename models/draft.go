@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type Draft struct {
+	ID         uint64         `json:"id" db:"id"`
+	UserID     uint64         `json:"user_id" db:"user_id"`
+	TargetType string         `json:"target_type" db:"target_type"` // "question", "answer", "edit_question" ou "edit_answer"
+	TargetID   uint64         `json:"target_id" db:"target_id"`     // 0 para nova pergunta
+	Title      string         `json:"title" db:"title"`
+	Body       string         `json:"body" db:"body"`
+	Tags       pq.StringArray `json:"tags" db:"tags"`
+	Version    int32          `json:"version" db:"version"`
+	CreatedAt  time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type LeaderboardSnapshot struct {
+	ID          uint64    `json:"id" db:"id"`
+	Period      string    `json:"period" db:"period"` // "week", "month", "season" ou "all"
+	Metric      string    `json:"metric" db:"metric"` // "reputation", "accepted" ou "answer_score"
+	PeriodStart time.Time `json:"period_start" db:"period_start"`
+	PeriodEnd   time.Time `json:"period_end" db:"period_end"`
+	ComputedAt  time.Time `json:"computed_at" db:"computed_at"`
+}
+
+type LeaderboardEntry struct {
+	SnapshotID uint64  `json:"snapshot_id" db:"snapshot_id"`
+	TagID      *uint64 `json:"tag_id" db:"tag_id"` // nulo no ranking geral
+	UserID     uint64  `json:"user_id" db:"user_id"`
+	Rank       int32   `json:"rank" db:"rank"`
+	Score      int32   `json:"score" db:"score"`
+}
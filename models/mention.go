@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type Mention struct {
+	ID              uint64    `json:"id" db:"id"`
+	PostType        string    `json:"post_type" db:"post_type"` // "question", "answer" ou "comment"
+	PostID          uint64    `json:"post_id" db:"post_id"`
+	MentionedUserID uint64    `json:"mentioned_user_id" db:"mentioned_user_id"`
+	AuthorID        uint64    `json:"author_id" db:"author_id"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+type Notification struct {
+	ID         uint64    `json:"id" db:"id"`
+	UserID     uint64    `json:"user_id" db:"user_id"`
+	Type       string    `json:"type" db:"type"` // "answer", "accepted", "upvote", "mention" ou "followed_answer"
+	ActorID    *uint64   `json:"actor_id" db:"actor_id"`
+	ActorCount int32     `json:"actor_count" db:"actor_count"` // pessoas agrupadas na notificação
+	PostType   string    `json:"post_type" db:"post_type"`
+	PostID     uint64    `json:"post_id" db:"post_id"`
+	QuestionID uint64    `json:"question_id" db:"question_id"`
+	GroupKey   string    `json:"-" db:"group_key"`
+	IsRead     bool      `json:"is_read" db:"is_read"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,7 @@
+package models
+
+type Privilege struct {
+	Name          string `json:"name" db:"name"`
+	MinReputation int32  `json:"min_reputation" db:"min_reputation"`
+	Description   string `json:"description" db:"description"`
+}
@@ -3,16 +3,36 @@ package models
 import "time"
 
 type Question struct {
-	ID          uint64    `json:"id" db:"id"`
-	UserID      uint64    `json:"user_id" db:"user_id"`
-	Title       string    `json:"title" db:"title"`
-	Body        string    `json:"body" db:"body"`
-	Votes       int32     `json:"votes" db:"votes"`
-	ViewCount   uint32    `json:"view_count" db:"view_count"`
-	AnswerCount uint32    `json:"answer_count" db:"answer_count"`
-	IsSolved    bool      `json:"is_solved" db:"is_solved"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID            uint64     `json:"id" db:"id"`
+	UserID        uint64     `json:"user_id" db:"user_id"`
+	Title         string     `json:"title" db:"title"`
+	Body          string     `json:"body" db:"body"`
+	BodyHTML      string     `json:"body_html" db:"body_html"` // Markdown renderizado e sanitizado
+	Votes         int32      `json:"votes" db:"votes"`
+	ViewCount     uint32     `json:"view_count" db:"view_count"`
+	AnswerCount   uint32     `json:"answer_count" db:"answer_count"`
+	IsSolved      bool       `json:"is_solved" db:"is_solved"`
+	ClosedAt      *time.Time `json:"closed_at" db:"closed_at"`
+	ClosedBy      *uint64    `json:"closed_by" db:"closed_by"`
+	CloseReason   *string    `json:"close_reason" db:"close_reason"` // "duplicate", "off-topic", "unclear" ou "resolved-in-game"
+	DuplicateOf   *uint64    `json:"duplicate_of" db:"duplicate_of"`
+	FeaturedUntil *time.Time `json:"featured_until" db:"featured_until"`
+	LockedAt      *time.Time `json:"locked_at" db:"locked_at"`
+	LockedBy      *uint64    `json:"locked_by" db:"locked_by"`
+	BookmarkCount uint32     `json:"bookmark_count" db:"bookmark_count"`
+	EditedAt      *time.Time `json:"edited_at" db:"edited_at"` // última edição do conteúdo pelo autor
+	DeletedAt     *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	DeletedBy     *uint64    `json:"deleted_by,omitempty" db:"deleted_by"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+
+	// Preenchido apenas nas listagens que consultam a recompensa ativa
+	BountyAmount    *int32     `json:"bounty_amount,omitempty" db:"bounty_amount"`
+	BountyExpiresAt *time.Time `json:"bounty_expires_at,omitempty" db:"bounty_expires_at"`
+
+	// Preenchidos apenas nas listagens de perguntas
+	IsPinned   bool `json:"is_pinned,omitempty" db:"is_pinned"`
+	IsFeatured bool `json:"is_featured,omitempty" db:"is_featured"`
 
 	// Relacionamentos
 	User    *User    `json:"user,omitempty"`
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+type QuestionPin struct {
+	ID         uint64    `json:"id" db:"id"`
+	QuestionID uint64    `json:"question_id" db:"question_id"`
+	TagID      *uint64   `json:"tag_id" db:"tag_id"` // nulo = fixada na listagem geral
+	PinnedBy   *uint64   `json:"pinned_by" db:"pinned_by"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
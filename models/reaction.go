@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+type ReactionType struct {
+	Name      string    `json:"name" db:"name"`           // código usado na API, ex.: "heart"
+	Emoji     *string   `json:"emoji" db:"emoji"`         // emoji Unicode
+	ImageURL  *string   `json:"image_url" db:"image_url"` // imagem das reações personalizadas
+	IsActive  bool      `json:"is_active" db:"is_active"`
+	Position  int32     `json:"position" db:"position"`
+	CreatedBy *uint64   `json:"created_by" db:"created_by"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type PostReaction struct {
+	UserID    uint64    `json:"user_id" db:"user_id"`
+	PostType  string    `json:"post_type" db:"post_type"` // "question" ou "answer"
+	PostID    uint64    `json:"post_id" db:"post_id"`
+	Reaction  string    `json:"reaction" db:"reaction"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type ReputationEvent struct {
+	ID        uint64    `json:"id" db:"id"`
+	UserID    uint64    `json:"user_id" db:"user_id"`
+	Delta     int32     `json:"delta" db:"delta"`
+	Reason    string    `json:"reason" db:"reason"` // ver reputation_handler.go
+	PostType  *string   `json:"post_type" db:"post_type"`
+	PostID    *uint64   `json:"post_id" db:"post_id"`
+	ActorID   *uint64   `json:"actor_id" db:"actor_id"` // quem causou (votante, autor da pergunta...)
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
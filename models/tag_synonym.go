@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type TagSynonym struct {
+	ID          uint64     `json:"id" db:"id"`
+	Name        string     `json:"name" db:"name"`
+	TagID       uint64     `json:"tag_id" db:"tag_id"` // tag principal
+	Status      string     `json:"status" db:"status"` // "pending", "approved" ou "rejected"
+	SuggestedBy *uint64    `json:"suggested_by" db:"suggested_by"`
+	ReviewedBy  *uint64    `json:"reviewed_by" db:"reviewed_by"`
+	ReviewedAt  *time.Time `json:"reviewed_at" db:"reviewed_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type VoteFraudReport struct {
+	ID         uint64          `json:"id" db:"id"`
+	Kind       string          `json:"kind" db:"kind"` // "serial" ou "ring"
+	VoterID    uint64          `json:"voter_id" db:"voter_id"`
+	TargetID   uint64          `json:"target_id" db:"target_id"` // no anel, o outro participante
+	VoteCount  int32           `json:"vote_count" db:"vote_count"`
+	Evidence   json.RawMessage `json:"evidence" db:"evidence"` // votos revertidos
+	Status     string          `json:"status" db:"status"`     // "open", "confirmed" ou "dismissed"
+	ReviewedBy *uint64         `json:"reviewed_by" db:"reviewed_by"`
+	ReviewedAt *time.Time      `json:"reviewed_at" db:"reviewed_at"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type Webhook struct {
+	ID        uint64         `json:"id" db:"id"`
+	URL       string         `json:"url" db:"url"`
+	Secret    string         `json:"secret" db:"secret"`
+	Format    string         `json:"format" db:"format"` // "json" ou "discord"
+	Events    pq.StringArray `json:"events" db:"events"`
+	Tags      pq.StringArray `json:"tags" db:"tags"` // vazio = todas as tags
+	IsActive  bool           `json:"is_active" db:"is_active"`
+	CreatedBy *uint64        `json:"created_by" db:"created_by"`
+	CreatedAt time.Time      `json:"created_at" db:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID             uint64          `json:"id" db:"id"`
+	WebhookID      uint64          `json:"webhook_id" db:"webhook_id"`
+	Event          string          `json:"event" db:"event"`
+	Payload        json.RawMessage `json:"payload" db:"payload"`
+	Status         string          `json:"status" db:"status"` // "pending", "success" ou "failed"
+	Attempts       int32           `json:"attempts" db:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at" db:"next_attempt_at"`
+	LastStatusCode *int32          `json:"last_status_code" db:"last_status_code"`
+	LastError      *string         `json:"last_error" db:"last_error"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at" db:"delivered_at"`
+}
@@ -0,0 +1,138 @@
+package realtime
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Broker transporta as mensagens entre instâncias da aplicação.
+// Publish envia para todas as instâncias (inclusive a atual) e Subscribe
+// registra quem recebe as mensagens nesta instância.
+type Broker interface {
+	Publish(topic string, payload []byte) error
+	Subscribe(handler func(topic string, payload []byte))
+}
+
+// LocalBroker entrega as mensagens apenas dentro do processo atual
+type LocalBroker struct {
+	mu       sync.RWMutex
+	handlers []func(topic string, payload []byte)
+}
+
+func NewLocalBroker() *LocalBroker {
+	return &LocalBroker{}
+}
+
+func (b *LocalBroker) Publish(topic string, payload []byte) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, handler := range b.handlers {
+		handler(topic, payload)
+	}
+	return nil
+}
+
+func (b *LocalBroker) Subscribe(handler func(topic string, payload []byte)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Canal usado no LISTEN/NOTIFY do PostgreSQL
+const postgresChannel = "forum_realtime"
+
+// O PostgreSQL rejeita payloads de NOTIFY com 8000 bytes ou mais
+const postgresMaxPayload = 8000
+
+// PostgresBroker usa LISTEN/NOTIFY para compartilhar eventos entre instâncias
+// conectadas ao mesmo banco. O payload do NOTIFY é limitado a ~8KB.
+type PostgresBroker struct {
+	LocalBroker
+	notify   func(channel, payload string) error
+	listener *pq.Listener
+}
+
+type postgresMessage struct {
+	Topic   string          `json:"topic"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// NewPostgresBroker abre um listener dedicado; notify executa pg_notify
+// usando o pool de conexões da aplicação
+func NewPostgresBroker(dsn string, notify func(channel, payload string) error) (*PostgresBroker, error) {
+	listener := pq.NewListener(dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Erro no listener do realtime: %v", err)
+		}
+	})
+	if err := listener.Listen(postgresChannel); err != nil {
+		return nil, err
+	}
+
+	b := &PostgresBroker{notify: notify, listener: listener}
+	go b.listen()
+	return b, nil
+}
+
+func (b *PostgresBroker) Publish(topic string, payload []byte) error {
+	message, err := json.Marshal(postgresMessage{Topic: topic, Payload: payload})
+	if err != nil {
+		return err
+	}
+
+	if len(message) >= postgresMaxPayload {
+		message, err = json.Marshal(postgresMessage{Topic: topic, Payload: idsOnly(payload)})
+		if err != nil {
+			return err
+		}
+	}
+	return b.notify(postgresChannel, string(message))
+}
+
+// idsOnly reduz os dados do evento aos campos de identificação (id e *_id) e marca
+// o evento como truncated; o cliente busca o restante pela API
+func idsOnly(payload []byte) []byte {
+	var event struct {
+		Topic string                     `json:"topic"`
+		Type  string                     `json:"type"`
+		Data  map[string]json.RawMessage `json:"data"`
+		At    time.Time                  `json:"at"`
+	}
+	// Eventos cujo data não é um objeto ficam sem dados
+	json.Unmarshal(payload, &event)
+
+	data := map[string]interface{}{"truncated": true}
+	for key, value := range event.Data {
+		if key == "id" || strings.HasSuffix(key, "_id") {
+			data[key] = value
+		}
+	}
+
+	reduced, _ := json.Marshal(Event{Topic: event.Topic, Type: event.Type, Data: data, At: event.At})
+	return reduced
+}
+
+func (b *PostgresBroker) listen() {
+	for {
+		select {
+		case n := <-b.listener.Notify:
+			if n == nil {
+				// Conexão restabelecida; eventos do intervalo foram perdidos
+				continue
+			}
+			var message postgresMessage
+			if err := json.Unmarshal([]byte(n.Extra), &message); err != nil {
+				log.Printf("Mensagem inválida no realtime: %v", err)
+				continue
+			}
+			b.LocalBroker.Publish(message.Topic, message.Payload)
+		case <-time.After(90 * time.Second):
+			go b.listener.Ping()
+		}
+	}
+}
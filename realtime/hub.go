@@ -0,0 +1,111 @@
+package realtime
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// Mensagens pendentes por conexão; quem não consome a tempo é desconectado
+const subscriberBuffer = 64
+
+// Event é o envelope entregue aos clientes
+type Event struct {
+	Topic string      `json:"topic"`
+	Type  string      `json:"type"`
+	Data  interface{} `json:"data"`
+	At    time.Time   `json:"at"`
+}
+
+// Subscriber representa uma conexão ouvindo um conjunto de tópicos
+type Subscriber struct {
+	C      chan []byte
+	topics []string
+	once   sync.Once
+	lagged bool
+}
+
+// Lagged indica que a conexão foi encerrada por não acompanhar o volume de eventos
+func (s *Subscriber) Lagged() bool {
+	return s.lagged
+}
+
+// Hub distribui os eventos recebidos do broker para os assinantes locais
+type Hub struct {
+	mu     sync.RWMutex
+	topics map[string]map[*Subscriber]struct{}
+	broker Broker
+}
+
+// NewHub cria o hub e passa a receber as mensagens do broker
+func NewHub(broker Broker) *Hub {
+	h := &Hub{
+		topics: map[string]map[*Subscriber]struct{}{},
+		broker: broker,
+	}
+	broker.Subscribe(h.dispatch)
+	return h
+}
+
+// Subscribe registra uma conexão nos tópicos informados
+func (h *Hub) Subscribe(topics []string) *Subscriber {
+	sub := &Subscriber{C: make(chan []byte, subscriberBuffer), topics: topics}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, topic := range topics {
+		if h.topics[topic] == nil {
+			h.topics[topic] = map[*Subscriber]struct{}{}
+		}
+		h.topics[topic][sub] = struct{}{}
+	}
+	return sub
+}
+
+// Unsubscribe remove a conexão de todos os tópicos e fecha seu canal
+func (h *Hub) Unsubscribe(sub *Subscriber) {
+	h.mu.Lock()
+	for _, topic := range sub.topics {
+		delete(h.topics[topic], sub)
+		if len(h.topics[topic]) == 0 {
+			delete(h.topics, topic)
+		}
+	}
+	h.mu.Unlock()
+
+	sub.once.Do(func() { close(sub.C) })
+}
+
+// Publish envia o evento pelo broker para todas as instâncias
+func (h *Hub) Publish(topic, eventType string, data interface{}) {
+	payload, err := json.Marshal(Event{Topic: topic, Type: eventType, Data: data, At: time.Now()})
+	if err != nil {
+		log.Printf("Erro ao serializar evento %s: %v", eventType, err)
+		return
+	}
+
+	if err := h.broker.Publish(topic, payload); err != nil {
+		log.Printf("Erro ao publicar evento %s em %s: %v", eventType, topic, err)
+	}
+}
+
+// dispatch entrega a mensagem aos assinantes locais sem bloquear o broker
+func (h *Hub) dispatch(topic string, payload []byte) {
+	var slow []*Subscriber
+
+	h.mu.RLock()
+	for sub := range h.topics[topic] {
+		select {
+		case sub.C <- payload:
+		default:
+			slow = append(slow, sub)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, sub := range slow {
+		sub.lagged = true
+		h.Unsubscribe(sub)
+	}
+}
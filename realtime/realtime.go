@@ -0,0 +1,16 @@
+package realtime
+
+import "fmt"
+
+// Default é o hub usado pelos handlers; substituído em main conforme o broker configurado
+var Default = NewHub(NewLocalBroker())
+
+// Publish envia um evento pelo hub padrão
+func Publish(topic, eventType string, data interface{}) {
+	Default.Publish(topic, eventType, data)
+}
+
+// Tópicos disponíveis para assinatura
+func QuestionTopic(id uint64) string { return fmt.Sprintf("question:%d", id) }
+func TagTopic(id uint64) string      { return fmt.Sprintf("tag:%d", id) }
+func UserTopic(id uint64) string     { return fmt.Sprintf("user:%d", id) }
@@ -0,0 +1,61 @@
+package render
+
+import (
+	"bytes"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// Pipeline: CommonMark + tabelas/riscado do GFM, seguido de sanitização por allow-list.
+// O HTML bruto digitado pelo usuário é escapado pelo goldmark (sem html.WithUnsafe)
+// e o sanitizador remove o que sobrar fora da lista permitida.
+var (
+	markdown = goldmark.New(
+		goldmark.WithExtensions(
+			extension.Table,
+			extension.Strikethrough,
+		),
+	)
+
+	policy = newPolicy()
+)
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+
+	p.AllowElements(
+		"p", "br", "hr", "blockquote",
+		"h1", "h2", "h3", "h4", "h5", "h6",
+		"ul", "ol", "li",
+		"strong", "em", "del", "code", "pre",
+		"table", "thead", "tbody", "tr",
+	)
+	p.AllowAttrs("align").Matching(bluemonday.SpaceSeparatedTokens).OnElements("th", "td")
+	p.AllowAttrs("start").Matching(bluemonday.Integer).OnElements("ol")
+
+	// Destaque de sintaxe no frontend depende da classe language-*
+	p.AllowAttrs("class").Matching(bluemonday.SpaceSeparatedTokens).OnElements("code")
+
+	// Links e imagens apenas com http/https e sem acesso ao opener
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowAttrs("title").OnElements("a", "img")
+	p.AllowAttrs("src", "alt").OnElements("img")
+	p.AllowURLSchemes("http", "https")
+	p.AllowRelativeURLs(true)
+	p.RequireParseableURLs(true)
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+
+	return p
+}
+
+// Markdown converte o corpo de um post em HTML sanitizado
+func Markdown(body string) (string, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(body), &buf); err != nil {
+		return "", err
+	}
+	return policy.Sanitize(buf.String()), nil
+}
@@ -0,0 +1,109 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdown(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "script tag",
+			body:    "<script>alert(1)</script>",
+			notWant: []string{"<script", "alert(1)"},
+		},
+		{
+			name:    "javascript link",
+			body:    "[x](javascript:alert(1))",
+			want:    []string{"<p>x</p>"},
+			notWant: []string{"href", "javascript:"},
+		},
+		{
+			name:    "data link",
+			body:    "[x](data:text/html;base64,PHNjcmlwdD4=)",
+			want:    []string{"<p>x</p>"},
+			notWant: []string{"href", "data:"},
+		},
+		{
+			name:    "entity-encoded javascript scheme",
+			body:    "[x](&#106;avascript:alert(1))",
+			notWant: []string{"href", "javascript:"},
+		},
+		{
+			name:    "entity-encoded tab inside javascript scheme",
+			body:    "[x](jav&#x09;ascript:alert(1))",
+			notWant: []string{"href", "ascript:"},
+		},
+		{
+			name:    "raw anchor with javascript href",
+			body:    `<a href="javascript:alert(1)">x</a>`,
+			notWant: []string{"href", "javascript:"},
+		},
+		{
+			name:    "img onerror",
+			body:    "<img src=x onerror=alert(1)>",
+			notWant: []string{"<img", "onerror"},
+		},
+		{
+			name:    "raw html block",
+			body:    "<div>\nraw\n</div>",
+			notWant: []string{"<div"},
+		},
+		{
+			name:    "iframe",
+			body:    `<iframe src="https://example.com"></iframe>`,
+			notWant: []string{"<iframe", "example.com"},
+		},
+		{
+			name:    "style attribute",
+			body:    `<p style="color:red">x</p>`,
+			notWant: []string{"style"},
+		},
+		{
+			name:    "table alignment does not leak style",
+			body:    "| a | b |\n|---|:-:|\n| 1 | 2 |",
+			want:    []string{"<table>", "<th>a</th>", "<td>1</td>"},
+			notWant: []string{"style"},
+		},
+		{
+			name: "http link gets nofollow and target blank",
+			body: "[ok](https://example.com)",
+			want: []string{`href="https://example.com"`, `rel="nofollow noopener"`, `target="_blank"`},
+		},
+		{
+			name: "image",
+			body: `![alt](https://example.com/a.png "title")`,
+			want: []string{`<img src="https://example.com/a.png" alt="alt" title="title">`},
+		},
+		{
+			name: "code block keeps language class",
+			body: "```go\nx := 1\n```",
+			want: []string{`<code class="language-go">`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, err := Markdown(tt.body)
+			if err != nil {
+				t.Fatalf("Markdown(%q) error: %v", tt.body, err)
+			}
+
+			for _, s := range tt.want {
+				if !strings.Contains(html, s) {
+					t.Errorf("Markdown(%q) = %q, want it to contain %q", tt.body, html, s)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(html, s) {
+					t.Errorf("Markdown(%q) = %q, must not contain %q", tt.body, html, s)
+				}
+			}
+		})
+	}
+}
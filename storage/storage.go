@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Storage guarda os arquivos enviados; implementações: disco local (S3 no futuro)
+type Storage interface {
+	Put(key string, r io.Reader) error
+	Delete(key string) error
+	// URL pública do arquivo
+	URL(key string) string
+}
+
+// Default é o armazenamento usado pelos handlers; configurado em main
+var Default Storage = &LocalStorage{Dir: "./assets/uploads", BaseURL: "/assets/uploads"}
+
+// NewFromEnv escolhe o armazenamento conforme STORAGE_DRIVER ("local")
+func NewFromEnv() (Storage, error) {
+	switch os.Getenv("STORAGE_DRIVER") {
+	case "local", "":
+		dir := os.Getenv("UPLOAD_DIR")
+		if dir == "" {
+			dir = "./assets/uploads"
+		}
+		baseURL := os.Getenv("UPLOAD_BASE_URL")
+		if baseURL == "" {
+			baseURL = "/assets/uploads"
+		}
+		return &LocalStorage{Dir: dir, BaseURL: baseURL}, nil
+	}
+
+	return nil, fmt.Errorf("STORAGE_DRIVER inválido: %s", os.Getenv("STORAGE_DRIVER"))
+}